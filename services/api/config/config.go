@@ -0,0 +1,300 @@
+// Package config loads the API server's settings.
+//
+// Every setting has a default and can be overridden, in increasing order of
+// precedence, by a JSON config file, an environment variable and a
+// command-line flag. The file is named by -config or BOOKSMART_CONFIG.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds the server settings.
+type Config struct {
+	Port int
+
+	// DatabaseURL selects PostgreSQL when set; otherwise SQLitePath is used.
+	DatabaseURL string
+	SQLitePath  string
+
+	AppName    string
+	AppVersion string
+
+	// StrictDevices rejects session requests for unregistered device_ids.
+	StrictDevices bool
+
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+
+	// ShutdownTimeout bounds how long in-flight requests get to finish after
+	// SIGTERM before the server closes them.
+	ShutdownTimeout time.Duration
+
+	// LogLevel is debug, info, warn or error; LogFormat is json or text.
+	LogLevel  string
+	LogFormat string
+
+	// OTLPEndpoint is the OTLP/HTTP collector URL spans are exported to,
+	// e.g. http://localhost:4318; empty disables export.
+	OTLPEndpoint string
+
+	// BackupDir receives scheduled SQLite backups and the snapshot taken
+	// before each restore. BackupInterval schedules a backup that often,
+	// 0 meaning never; only the newest BackupKeep scheduled ones are kept.
+	BackupDir      string
+	BackupInterval time.Duration
+	BackupKeep     int
+
+	// TraceSampleRatio is the fraction of new traces sampled, 0 to 1.
+	// Requests that arrive with a sampled parent are always traced.
+	TraceSampleRatio float64
+}
+
+// Default returns the settings used when nothing overrides them.
+func Default() Config {
+	return Config{
+		Port:              8787,
+		SQLitePath:        "./data/reading.sqlite",
+		AppName:           "booksmart",
+		AppVersion:        "dev",
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       15 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       60 * time.Second,
+		ShutdownTimeout:   20 * time.Second,
+		LogLevel:          "info",
+		LogFormat:         "json",
+		TraceSampleRatio:  1,
+		BackupKeep:        7,
+	}
+}
+
+// Addr is the address to listen on.
+func (c Config) Addr() string {
+	return ":" + strconv.Itoa(c.Port)
+}
+
+// DSN names the database for handlers.OpenStore.
+func (c Config) DSN() string {
+	if c.DatabaseURL != "" {
+		return c.DatabaseURL
+	}
+	return c.SQLitePath
+}
+
+// setting ties one Config field to its file key, environment variable and
+// flag. All three sources are parsed from the same text form.
+type setting struct {
+	key   string
+	env   string
+	usage string
+	set   func(c *Config, v string) error
+}
+
+var settings = []setting{
+	{"port", "PORT", "port to listen on", func(c *Config, v string) error { return setInt(&c.Port, v) }},
+	{"database_url", "DATABASE_URL", "PostgreSQL URL (postgres://…); SQLite is used when empty", func(c *Config, v string) error { c.DatabaseURL = v; return nil }},
+	{"sqlite_path", "SQLITE_PATH", "SQLite database file", func(c *Config, v string) error { c.SQLitePath = v; return nil }},
+	{"app_name", "APP_NAME", "name reported by /v1/version", func(c *Config, v string) error { c.AppName = v; return nil }},
+	{"app_version", "APP_VERSION", "version reported by /v1/version", func(c *Config, v string) error { c.AppVersion = v; return nil }},
+	{"strict_devices", "STRICT_DEVICES", "reject session requests for unregistered devices", func(c *Config, v string) error { return setBool(&c.StrictDevices, v) }},
+	{"read_header_timeout", "READ_HEADER_TIMEOUT", "time allowed to read request headers", func(c *Config, v string) error { return setDuration(&c.ReadHeaderTimeout, v) }},
+	{"read_timeout", "READ_TIMEOUT", "time allowed to read a whole request", func(c *Config, v string) error { return setDuration(&c.ReadTimeout, v) }},
+	{"write_timeout", "WRITE_TIMEOUT", "time allowed to write a response", func(c *Config, v string) error { return setDuration(&c.WriteTimeout, v) }},
+	{"idle_timeout", "IDLE_TIMEOUT", "how long idle keep-alive connections stay open", func(c *Config, v string) error { return setDuration(&c.IdleTimeout, v) }},
+	{"shutdown_timeout", "SHUTDOWN_TIMEOUT", "how long to drain requests on SIGTERM", func(c *Config, v string) error { return setDuration(&c.ShutdownTimeout, v) }},
+	{"log_level", "LOG_LEVEL", "debug, info, warn or error", func(c *Config, v string) error { c.LogLevel = strings.ToLower(v); return nil }},
+	{"log_format", "LOG_FORMAT", "json or text", func(c *Config, v string) error { c.LogFormat = strings.ToLower(v); return nil }},
+	{"otlp_endpoint", "OTEL_EXPORTER_OTLP_ENDPOINT", "OTLP/HTTP collector URL for traces; export is off when empty", func(c *Config, v string) error { c.OTLPEndpoint = v; return nil }},
+	{"trace_sample_ratio", "TRACE_SAMPLE_RATIO", "fraction of new traces to sample, 0 to 1", func(c *Config, v string) error { return setFloat(&c.TraceSampleRatio, v) }},
+	{"backup_dir", "BACKUP_DIR", "directory for scheduled and pre-restore SQLite backups", func(c *Config, v string) error { c.BackupDir = v; return nil }},
+	{"backup_interval", "BACKUP_INTERVAL", "how often to back up into backup_dir; 0 disables", func(c *Config, v string) error { return setDuration(&c.BackupInterval, v) }},
+	{"backup_keep", "BACKUP_KEEP", "number of scheduled backups to keep", func(c *Config, v string) error { return setInt(&c.BackupKeep, v) }},
+}
+
+// flagName turns a file key into a flag name: read_timeout → read-timeout.
+func (s setting) flagName() string {
+	return strings.ReplaceAll(s.key, "_", "-")
+}
+
+// Load builds the config from defaults, the config file, the environment
+// (read through getenv) and args, the command-line flags without the
+// program name.
+func Load(args []string, getenv func(string) string) (Config, error) {
+	fs := flag.NewFlagSet("booksmart", flag.ContinueOnError)
+	file := fs.String("config", getenv("BOOKSMART_CONFIG"), "JSON config file")
+	flags := map[string]*string{}
+	for _, s := range settings {
+		flags[s.key] = fs.String(s.flagName(), "", s.usage+" ($"+s.env+")")
+	}
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	c := Default()
+	if *file != "" {
+		if err := c.loadFile(*file); err != nil {
+			return Config{}, err
+		}
+	}
+	for _, s := range settings {
+		if v := getenv(s.env); v != "" {
+			if err := s.set(&c, v); err != nil {
+				return Config{}, fmt.Errorf("%s: %w", s.env, err)
+			}
+		}
+	}
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		for _, s := range settings {
+			if err == nil && f.Name == s.flagName() {
+				if e := s.set(&c, *flags[s.key]); e != nil {
+					err = fmt.Errorf("-%s: %w", f.Name, e)
+				}
+			}
+		}
+	})
+	if err != nil {
+		return Config{}, err
+	}
+
+	if err := c.Validate(); err != nil {
+		return Config{}, err
+	}
+	return c, nil
+}
+
+// loadFile applies a JSON object of settings keyed like "read_timeout".
+// Values may be JSON strings or bare numbers and booleans; unknown keys are
+// rejected so typos do not go unnoticed.
+func (c *Config) loadFile(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config: %w", err)
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return fmt.Errorf("parse config %s: %w", path, err)
+	}
+
+	for _, s := range settings {
+		msg, ok := raw[s.key]
+		if !ok {
+			continue
+		}
+		delete(raw, s.key)
+		v := string(msg)
+		var str string
+		if json.Unmarshal(msg, &str) == nil {
+			v = str
+		}
+		if err := s.set(c, v); err != nil {
+			return fmt.Errorf("config %s: %s: %w", path, s.key, err)
+		}
+	}
+	for k := range raw {
+		return fmt.Errorf("config %s: unknown setting %q", path, k)
+	}
+	return nil
+}
+
+// Validate reports the first setting that cannot work.
+func (c Config) Validate() error {
+	if c.Port < 1 || c.Port > 65535 {
+		return fmt.Errorf("port must be between 1 and 65535, got %d", c.Port)
+	}
+	if c.DatabaseURL != "" && !strings.HasPrefix(c.DatabaseURL, "postgres://") && !strings.HasPrefix(c.DatabaseURL, "postgresql://") {
+		return errors.New("database_url must be a postgres:// or postgresql:// URL")
+	}
+	switch c.LogLevel {
+	case "debug", "info", "warn", "error":
+	default:
+		return fmt.Errorf("log_level must be debug, info, warn or error, got %q", c.LogLevel)
+	}
+	if c.LogFormat != "json" && c.LogFormat != "text" {
+		return fmt.Errorf("log_format must be json or text, got %q", c.LogFormat)
+	}
+	if c.OTLPEndpoint != "" && !strings.HasPrefix(c.OTLPEndpoint, "http://") && !strings.HasPrefix(c.OTLPEndpoint, "https://") {
+		return errors.New("otlp_endpoint must be an http:// or https:// URL")
+	}
+	if c.TraceSampleRatio < 0 || c.TraceSampleRatio > 1 {
+		return fmt.Errorf("trace_sample_ratio must be between 0 and 1, got %g", c.TraceSampleRatio)
+	}
+	if c.BackupInterval < 0 {
+		return fmt.Errorf("backup_interval must be >= 0, got %s", c.BackupInterval)
+	}
+	if c.BackupInterval > 0 {
+		if c.BackupDir == "" {
+			return errors.New("backup_dir is required when backup_interval is set")
+		}
+		if c.DatabaseURL != "" {
+			return errors.New("backup_interval is SQLite only; back up PostgreSQL with pg_dump")
+		}
+	}
+	if c.BackupKeep < 1 {
+		return fmt.Errorf("backup_keep must be >= 1, got %d", c.BackupKeep)
+	}
+	if c.DatabaseURL == "" && c.SQLitePath == "" {
+		return errors.New("sqlite_path is required when database_url is not set")
+	}
+	for _, t := range []struct {
+		name string
+		d    time.Duration
+	}{
+		{"read_header_timeout", c.ReadHeaderTimeout},
+		{"read_timeout", c.ReadTimeout},
+		{"write_timeout", c.WriteTimeout},
+		{"idle_timeout", c.IdleTimeout},
+		{"shutdown_timeout", c.ShutdownTimeout},
+	} {
+		if t.d <= 0 {
+			return fmt.Errorf("%s must be > 0, got %s", t.name, t.d)
+		}
+	}
+	return nil
+}
+
+func setInt(dst *int, v string) error {
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("%q is not a whole number", v)
+	}
+	*dst = n
+	return nil
+}
+
+func setBool(dst *bool, v string) error {
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return fmt.Errorf("%q is not true or false", v)
+	}
+	*dst = b
+	return nil
+}
+
+func setFloat(dst *float64, v string) error {
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return fmt.Errorf("%q is not a number", v)
+	}
+	*dst = f
+	return nil
+}
+
+func setDuration(dst *time.Duration, v string) error {
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return fmt.Errorf("%q is not a duration like 15s", v)
+	}
+	*dst = d
+	return nil
+}
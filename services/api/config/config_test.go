@@ -0,0 +1,93 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mk-slmn/booksmart/services/api/config"
+)
+
+func env(m map[string]string) func(string) string {
+	return func(k string) string { return m[k] }
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	c, err := config.Load(nil, env(nil))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if c != config.Default() {
+		t.Fatalf("expected defaults, got %+v", c)
+	}
+	if c.Addr() != ":8787" || c.DSN() != "./data/reading.sqlite" {
+		t.Fatalf("unexpected addr %q / dsn %q", c.Addr(), c.DSN())
+	}
+}
+
+func TestLoad_FileThenEnvThenFlags(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "booksmart.json")
+	err := os.WriteFile(path, []byte(`{
+		"port": 9000,
+		"sqlite_path": "/var/lib/booksmart.sqlite",
+		"strict_devices": true,
+		"write_timeout": "45s",
+		"app_name": "from-file"
+	}`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := config.Load(
+		[]string{"-port", "9100", "-shutdown-timeout", "5s"},
+		env(map[string]string{"BOOKSMART_CONFIG": path, "PORT": "9050", "APP_NAME": "from-env"}),
+	)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if c.Port != 9100 {
+		t.Fatalf("flag should win for port, got %d", c.Port)
+	}
+	if c.AppName != "from-env" {
+		t.Fatalf("env should beat file, got %q", c.AppName)
+	}
+	if c.SQLitePath != "/var/lib/booksmart.sqlite" || !c.StrictDevices || c.WriteTimeout != 45*time.Second {
+		t.Fatalf("file settings not applied: %+v", c)
+	}
+	if c.ShutdownTimeout != 5*time.Second || c.ReadTimeout != config.Default().ReadTimeout {
+		t.Fatalf("unexpected timeouts: %+v", c)
+	}
+}
+
+func TestLoad_RejectsBadSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "booksmart.json")
+	if err := os.WriteFile(path, []byte(`{"prot": 9000}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		args []string
+		env  map[string]string
+		want string
+	}{
+		{nil, map[string]string{"PORT": "eighty"}, "PORT"},
+		{[]string{"-port", "70000"}, nil, "port must be between"},
+		{nil, map[string]string{"WRITE_TIMEOUT": "0s"}, "write_timeout must be > 0"},
+		{nil, map[string]string{"DATABASE_URL": "mysql://x"}, "database_url"},
+		{nil, map[string]string{"LOG_LEVEL": "verbose"}, "log_level"},
+		{nil, map[string]string{"TRACE_SAMPLE_RATIO": "1.5"}, "trace_sample_ratio"},
+		{nil, map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "localhost:4318"}, "otlp_endpoint"},
+		{nil, map[string]string{"BACKUP_INTERVAL": "24h"}, "backup_dir is required"},
+		{nil, map[string]string{"BACKUP_KEEP": "0"}, "backup_keep"},
+		{[]string{"-config", path}, nil, `unknown setting "prot"`},
+		{[]string{"-no-such-flag"}, nil, "not defined"},
+	}
+	for _, tc := range cases {
+		_, err := config.Load(tc.args, env(tc.env))
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("args=%v env=%v: expected error containing %q, got %v", tc.args, tc.env, tc.want, err)
+		}
+	}
+}
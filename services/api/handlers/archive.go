@@ -0,0 +1,606 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// An archive is one user's library as a self-describing JSON document, for
+// moving between servers whatever database either one runs on.
+//
+// archiveVersion is bumped only when an older server would misread an
+// archive. New optional fields are added without a bump; importers ignore
+// fields they do not know and treat missing ones as empty. Version 2 added
+// notes, which version 1 servers would drop without a word.
+const (
+	archiveFormat   = "booksmart-archive"
+	archiveVersion  = 2
+	archiveMaxBytes = 64 << 20
+)
+
+// Conflict policies for books in an archive whose title the user already has.
+const (
+	conflictSkip      = "skip"      // keep the existing book, its sessions and notes; import nothing for it
+	conflictOverwrite = "overwrite" // replace the book's fields, sessions and notes with the archive's
+	conflictMerge     = "merge"     // keep the book, fill its empty fields, add sessions and notes it lacks
+)
+
+type archive struct {
+	Format     string           `json:"format"`
+	Version    int              `json:"version"`
+	ExportedAt string           `json:"exported_at"`
+	Server     archiveServer    `json:"server"`
+	User       string           `json:"user"`
+	Books      []archiveBook    `json:"books"`
+	Sessions   []archiveSession `json:"sessions"`
+	Devices    []archiveDevice  `json:"devices"`
+	Goals      []archiveGoal    `json:"goals"`
+	Notes      []archiveNote    `json:"notes"`
+}
+
+type archiveServer struct {
+	Name          string `json:"name"`
+	Version       string `json:"version"`
+	SchemaVersion int    `json:"schema_version"`
+}
+
+// archiveBook.ID and archiveSession.ID are the exporting server's; they only
+// link sessions and notes to books and sessions within the archive and are
+// remapped on import.
+type archiveBook struct {
+	ID         int64   `json:"id"`
+	Title      string  `json:"title"`
+	Author     *string `json:"author"`
+	Source     *string `json:"source"`
+	TotalPages *int    `json:"total_pages"`
+	CreatedAt  string  `json:"created_at"`
+	bookCatalog
+}
+
+type archiveSession struct {
+	ID              int64   `json:"id"`
+	BookID          int64   `json:"book_id"`
+	DeviceID        string  `json:"device_id"`
+	StartPage       int     `json:"start_page"`
+	EndPage         *int    `json:"end_page"`
+	StartedAt       string  `json:"started_at"`
+	EndedAt         *string `json:"ended_at"`
+	DurationSeconds *int64  `json:"duration_seconds"`
+	CreatedAt       string  `json:"created_at"`
+}
+
+type archiveDevice struct {
+	DeviceID  string `json:"device_id"`
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Timezone  string `json:"timezone"`
+	CreatedAt string `json:"created_at"`
+}
+
+type archiveNote struct {
+	BookID        int64    `json:"book_id"`
+	SessionID     *int64   `json:"session_id"`
+	Kind          string   `json:"kind"`
+	Text          string   `json:"text"`
+	Tags          []string `json:"tags"`
+	Page          *int     `json:"page"`
+	LocationStart *int     `json:"location_start"`
+	LocationEnd   *int     `json:"location_end"`
+	ClippedAt     *string  `json:"clipped_at"`
+	Source        *string  `json:"source"`
+	CreatedAt     string   `json:"created_at"`
+	UpdatedAt     *string  `json:"updated_at"`
+}
+
+type archiveGoal struct {
+	Kind      string `json:"kind"`
+	Target    int    `json:"target"`
+	CreatedAt string `json:"created_at"`
+}
+
+// importCounts tallies what an import did with one kind of record.
+type importCounts struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	Skipped int `json:"skipped"`
+}
+
+type importResult struct {
+	Conflict string       `json:"conflict"`
+	Books    importCounts `json:"books"`
+	Sessions importCounts `json:"sessions"`
+	Devices  importCounts `json:"devices"`
+	Goals    importCounts `json:"goals"`
+	Notes    importCounts `json:"notes"`
+}
+
+// exportArchive answers with the user's whole library. It is read in one
+// transaction so sessions and notes never point at books or sessions the
+// archive lacks.
+func (a *App) exportArchive(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	uid := userID(ctx)
+	v := a.versionInfo()
+	out := archive{
+		Format:     archiveFormat,
+		Version:    archiveVersion,
+		ExportedAt: timeOrNowRFC3339(nil),
+		Server:     archiveServer{Name: v.Name, Version: v.Version, SchemaVersion: SchemaVersion()},
+	}
+
+	err := a.Store.WithTx(ctx, func(ctx context.Context, tx Querier) error {
+		out.Books, out.Sessions = []archiveBook{}, []archiveSession{}
+		out.Devices, out.Goals, out.Notes = []archiveDevice{}, []archiveGoal{}, []archiveNote{}
+		if err := tx.QueryRow(ctx, `SELECT name FROM users WHERE id = ?`, uid).Scan(&out.User); err != nil {
+			return err
+		}
+		var scanned []func()
+		if err := scanAll(ctx, tx, &out.Books, func(b *archiveBook) []any {
+			catalog, done := b.scanFields()
+			scanned = append(scanned, done)
+			return append([]any{&b.ID, &b.Title, &b.Author, &b.Source, &b.TotalPages, &b.CreatedAt}, catalog...)
+		}, `SELECT id, title, author, source, total_pages, created_at, `+bookCatalogColumns+` FROM books WHERE user_id = ? ORDER BY id`, uid); err != nil {
+			return err
+		}
+		for _, done := range scanned {
+			done()
+		}
+		if err := scanAll(ctx, tx, &out.Sessions, func(s *archiveSession) []any {
+			return []any{&s.ID, &s.BookID, &s.DeviceID, &s.StartPage, &s.EndPage, &s.StartedAt, &s.EndedAt, &s.DurationSeconds, &s.CreatedAt}
+		}, `
+			SELECT id, book_id, device_id, start_page, end_page, started_at, ended_at, duration_seconds, created_at
+			FROM sessions WHERE user_id = ? ORDER BY id
+		`, uid); err != nil {
+			return err
+		}
+		if err := scanAll(ctx, tx, &out.Devices, func(d *archiveDevice) []any {
+			return []any{&d.DeviceID, &d.Name, &d.Type, &d.Timezone, &d.CreatedAt}
+		}, `SELECT device_id, name, type, timezone, created_at FROM devices WHERE user_id = ? ORDER BY id`, uid); err != nil {
+			return err
+		}
+		if err := scanAll(ctx, tx, &out.Goals, func(g *archiveGoal) []any {
+			return []any{&g.Kind, &g.Target, &g.CreatedAt}
+		}, `SELECT kind, target, created_at FROM goals WHERE user_id = ? ORDER BY id`, uid); err != nil {
+			return err
+		}
+		tags := []*string{}
+		if err := scanAll(ctx, tx, &out.Notes, func(n *archiveNote) []any {
+			tags = append(tags, nil)
+			return []any{&n.BookID, &n.SessionID, &n.Kind, &n.Text, &tags[len(tags)-1], &n.Page, &n.LocationStart, &n.LocationEnd, &n.ClippedAt, &n.Source, &n.CreatedAt, &n.UpdatedAt}
+		}, `
+			SELECT book_id, session_id, kind, text, tags, page, location_start, location_end, clipped_at, source, created_at, updated_at
+			FROM notes WHERE user_id = ? ORDER BY id
+		`, uid); err != nil {
+			return err
+		}
+		for i := range out.Notes {
+			out.Notes[i].Tags = append([]string{}, splitList(tags[i])...)
+		}
+		return nil
+	})
+	if err != nil {
+		serverError(w, r, err, "query failed")
+		return
+	}
+
+	w.Header().Set("Content-Disposition", `attachment; filename="booksmart-archive.json"`)
+	writeJSON(w, http.StatusOK, out)
+}
+
+// scanAll appends a T to dst for every row of query, scanning into the
+// pointers fields returns.
+func scanAll[T any](ctx context.Context, q Querier, dst *[]T, fields func(*T) []any, query string, args ...any) error {
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var v T
+		if err := rows.Scan(fields(&v)...); err != nil {
+			return err
+		}
+		*dst = append(*dst, v)
+	}
+	return rows.Err()
+}
+
+// importArchive loads an archive into the user's library in one
+// transaction. Books are matched by title like startSession does, and
+// ?conflict= (skip, overwrite or merge; default skip) decides what happens
+// to books, devices and goals the user already has. Archive ids are
+// remapped, so an archive can be imported into a non-empty server.
+func (a *App) importArchive(w http.ResponseWriter, r *http.Request) {
+	policy := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("conflict")))
+	switch policy {
+	case "":
+		policy = conflictSkip
+	case conflictSkip, conflictOverwrite, conflictMerge:
+	default:
+		writeError(w, r, ErrInvalidValue.Field("conflict", "conflict must be skip, overwrite or merge"))
+		return
+	}
+
+	var ar archive
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, archiveMaxBytes)).Decode(&ar); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, r, ErrTooLarge.WithMessage(fmt.Sprintf("archives are limited to %d bytes", archiveMaxBytes)))
+			return
+		}
+		writeError(w, r, ErrInvalidJSON)
+		return
+	}
+	if err := ar.validate(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	uid := userID(ctx)
+	var res importResult
+	err := a.Store.WithTx(ctx, func(ctx context.Context, tx Querier) error {
+		res = importResult{Conflict: policy}
+		if err := importDevices(ctx, tx, uid, policy, ar.Devices, &res.Devices); err != nil {
+			return err
+		}
+		if err := importGoals(ctx, tx, uid, policy, ar.Goals, &res.Goals); err != nil {
+			return err
+		}
+		return importLibrary(ctx, tx, uid, policy, &ar, &res)
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	a.metrics.booksCreated.Add(float64(res.Books.Created))
+	writeJSON(w, http.StatusOK, res)
+}
+
+// validate checks the whole archive before anything is written, and
+// normalizes times to RFC3339 UTC.
+func (ar *archive) validate() error {
+	if ar.Format != archiveFormat {
+		return ErrInvalidArchive.Field("format", `format must be "`+archiveFormat+`"`)
+	}
+	if ar.Version < 1 || ar.Version > archiveVersion {
+		return ErrInvalidArchive.Field("version", fmt.Sprintf("archive version %d is not supported; this server reads up to %d", ar.Version, archiveVersion))
+	}
+	now := timeOrNowRFC3339(nil)
+	normTime := func(field string, s *string, required bool) error {
+		if strings.TrimSpace(*s) == "" {
+			if required {
+				return ErrInvalidArchive.Field(field, field+" is required")
+			}
+			*s = now
+			return nil
+		}
+		t, err := parseRFC3339UTC(*s)
+		if err != nil {
+			return ErrInvalidArchive.Field(field, field+" must be RFC3339")
+		}
+		*s = t.Format(time.RFC3339)
+		return nil
+	}
+
+	books := make(map[int64]bool, len(ar.Books))
+	titles := make(map[string]bool, len(ar.Books))
+	for i := range ar.Books {
+		b := &ar.Books[i]
+		f := fmt.Sprintf("books[%d].", i)
+		b.Title = strings.TrimSpace(b.Title)
+		switch {
+		case b.ID <= 0 || books[b.ID]:
+			return ErrInvalidArchive.Field(f+"id", "book ids must be positive and unique")
+		case b.Title == "":
+			return ErrInvalidArchive.Field(f+"title", "title is required")
+		case titles[b.Title]:
+			return ErrInvalidArchive.Field(f+"title", "book titles must be unique")
+		case b.TotalPages != nil && *b.TotalPages <= 0:
+			return ErrInvalidArchive.Field(f+"total_pages", "total_pages must be > 0")
+		}
+		if err := normTime(f+"created_at", &b.CreatedAt, false); err != nil {
+			return err
+		}
+		if err := b.bookCatalog.validate(f); err != nil {
+			return err
+		}
+		books[b.ID], titles[b.Title] = true, true
+	}
+
+	sessions := make(map[int64]int64, len(ar.Sessions)) // session id → book id
+	for i := range ar.Sessions {
+		s := &ar.Sessions[i]
+		f := fmt.Sprintf("sessions[%d].", i)
+		s.DeviceID = strings.TrimSpace(s.DeviceID)
+		_, dup := sessions[s.ID]
+		switch {
+		case s.ID != 0 && dup:
+			return ErrInvalidArchive.Field(f+"id", "session ids must be unique")
+		case !books[s.BookID]:
+			return ErrInvalidArchive.Field(f+"book_id", "book_id must name a book in the archive")
+		case s.DeviceID == "":
+			return ErrInvalidArchive.Field(f+"device_id", "device_id is required")
+		case s.StartPage < 0 || (s.EndPage != nil && *s.EndPage < 0):
+			return ErrInvalidArchive.Field(f+"start_page", "pages must be >= 0")
+		case s.DurationSeconds != nil && *s.DurationSeconds < 0:
+			return ErrInvalidArchive.Field(f+"duration_seconds", "duration_seconds must be >= 0")
+		}
+		if err := normTime(f+"started_at", &s.StartedAt, true); err != nil {
+			return err
+		}
+		if s.EndedAt != nil {
+			if err := normTime(f+"ended_at", s.EndedAt, true); err != nil {
+				return err
+			}
+		}
+		if err := normTime(f+"created_at", &s.CreatedAt, false); err != nil {
+			return err
+		}
+		if s.ID != 0 {
+			sessions[s.ID] = s.BookID
+		}
+	}
+
+	for i := range ar.Devices {
+		d := &ar.Devices[i]
+		f := fmt.Sprintf("devices[%d].", i)
+		d.DeviceID, d.Name = strings.TrimSpace(d.DeviceID), strings.TrimSpace(d.Name)
+		if d.Timezone == "" {
+			d.Timezone = "UTC"
+		}
+		switch {
+		case d.DeviceID == "":
+			return ErrInvalidArchive.Field(f+"device_id", "device_id is required")
+		case d.Name == "":
+			return ErrInvalidArchive.Field(f+"name", "name is required")
+		case !deviceTypes[d.Type]:
+			return ErrInvalidArchive.Field(f+"type", "type must be one of iphone, ipad, mac, ereader, other")
+		}
+		if _, err := time.LoadLocation(d.Timezone); err != nil {
+			return ErrInvalidArchive.Field(f+"timezone", "timezone must be an IANA time zone")
+		}
+		if err := normTime(f+"created_at", &d.CreatedAt, false); err != nil {
+			return err
+		}
+	}
+
+	for i := range ar.Goals {
+		g := &ar.Goals[i]
+		f := fmt.Sprintf("goals[%d].", i)
+		switch g.Kind {
+		case goalBooksPerYear, goalMinutesPerDay, goalPagesPerWeek:
+		default:
+			return ErrInvalidArchive.Field(f+"kind", "kind must be one of books_per_year, minutes_per_day, pages_per_week")
+		}
+		if g.Target <= 0 {
+			return ErrInvalidArchive.Field(f+"target", "target must be > 0")
+		}
+		if err := normTime(f+"created_at", &g.CreatedAt, false); err != nil {
+			return err
+		}
+	}
+
+	for i := range ar.Notes {
+		n := &ar.Notes[i]
+		f := fmt.Sprintf("notes[%d].", i)
+		req := noteRequest{BookID: &n.BookID, SessionID: n.SessionID, Kind: n.Kind, Text: n.Text, Page: n.Page, Tags: n.Tags}
+		if err := req.validate(); err != nil {
+			var e *Error
+			if errors.As(err, &e) && len(e.Details) > 0 {
+				return ErrInvalidArchive.Field(f+e.Details[0].Field, e.Details[0].Message)
+			}
+			return err
+		}
+		n.Kind, n.Text, n.Tags = req.Kind, req.Text, req.Tags
+		switch {
+		case !books[n.BookID]:
+			return ErrInvalidArchive.Field(f+"book_id", "book_id must name a book in the archive")
+		case n.SessionID != nil && sessions[*n.SessionID] != n.BookID:
+			return ErrInvalidArchive.Field(f+"session_id", "session_id must name a session of the note's book in the archive")
+		}
+		if err := normTime(f+"created_at", &n.CreatedAt, false); err != nil {
+			return err
+		}
+		for field, t := range map[string]*string{"clipped_at": n.ClippedAt, "updated_at": n.UpdatedAt} {
+			if t != nil {
+				if err := normTime(f+field, t, true); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func importDevices(ctx context.Context, tx Querier, uid int64, policy string, devices []archiveDevice, n *importCounts) error {
+	for _, d := range devices {
+		var exists int
+		if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM devices WHERE user_id = ? AND device_id = ?`, uid, d.DeviceID).Scan(&exists); err != nil {
+			return err
+		}
+		switch {
+		case exists == 0:
+			if _, err := tx.Exec(ctx, `
+				INSERT INTO devices (user_id, device_id, name, type, timezone, created_at)
+				VALUES (?, ?, ?, ?, ?, ?)
+			`, uid, d.DeviceID, d.Name, d.Type, d.Timezone, d.CreatedAt); err != nil {
+				return err
+			}
+			n.Created++
+		case policy == conflictOverwrite:
+			if _, err := tx.Exec(ctx, `
+				UPDATE devices SET name = ?, type = ?, timezone = ?
+				WHERE user_id = ? AND device_id = ?
+			`, d.Name, d.Type, d.Timezone, uid, d.DeviceID); err != nil {
+				return err
+			}
+			n.Updated++
+		default:
+			n.Skipped++
+		}
+	}
+	return nil
+}
+
+func importGoals(ctx context.Context, tx Querier, uid int64, policy string, goals []archiveGoal, n *importCounts) error {
+	for _, g := range goals {
+		var exists int
+		if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM goals WHERE user_id = ? AND kind = ?`, uid, g.Kind).Scan(&exists); err != nil {
+			return err
+		}
+		switch {
+		case exists == 0:
+			if _, err := tx.Exec(ctx, `INSERT INTO goals (user_id, kind, target, created_at) VALUES (?, ?, ?, ?)`,
+				uid, g.Kind, g.Target, g.CreatedAt); err != nil {
+				return err
+			}
+			n.Created++
+		case policy == conflictOverwrite:
+			if _, err := tx.Exec(ctx, `UPDATE goals SET target = ? WHERE user_id = ? AND kind = ?`, g.Target, uid, g.Kind); err != nil {
+				return err
+			}
+			n.Updated++
+		default:
+			n.Skipped++
+		}
+	}
+	return nil
+}
+
+// importLibrary imports books, then their sessions, then their notes,
+// remapping archive book and session ids to local ones. Sessions and notes
+// of skipped books are skipped with them; a note whose session was skipped
+// keeps its book.
+func importLibrary(ctx context.Context, tx Querier, uid int64, policy string, ar *archive, res *importResult) error {
+	local := make(map[int64]int64, len(ar.Books))            // archive book id → local id, absent when skipped
+	localSessions := make(map[int64]int64, len(ar.Sessions)) // archive session id → local id
+	merging := make(map[int64]bool)                          // local ids of existing books sessions and notes are merged into
+	for _, b := range ar.Books {
+		id, err := findBookIDByTitle(ctx, tx, uid, b.Title)
+		if err != nil {
+			return err
+		}
+		switch {
+		case id == 0:
+			if id, err = insertBook(ctx, tx, uid, b.Title, b.Author, b.Source, b.TotalPages, b.CreatedAt); err != nil {
+				return err
+			}
+			if err := setBookCatalog(ctx, tx, id, b.bookCatalog, false); err != nil {
+				return err
+			}
+			res.Books.Created++
+		case policy == conflictOverwrite:
+			if _, err := tx.Exec(ctx, `UPDATE books SET author = ?, source = ?, total_pages = ? WHERE id = ?`,
+				b.Author, b.Source, b.TotalPages, id); err != nil {
+				return err
+			}
+			if err := setBookCatalog(ctx, tx, id, b.bookCatalog, false); err != nil {
+				return err
+			}
+			if _, err := tx.Exec(ctx, `DELETE FROM notes WHERE book_id = ?`, id); err != nil {
+				return err
+			}
+			if _, err := tx.Exec(ctx, `DELETE FROM sessions WHERE book_id = ?`, id); err != nil {
+				return err
+			}
+			res.Books.Updated++
+		case policy == conflictMerge:
+			if _, err := tx.Exec(ctx, `
+				UPDATE books
+				SET author = COALESCE(author, ?), source = COALESCE(source, ?), total_pages = COALESCE(total_pages, ?)
+				WHERE id = ?
+			`, b.Author, b.Source, b.TotalPages, id); err != nil {
+				return err
+			}
+			if err := setBookCatalog(ctx, tx, id, b.bookCatalog, true); err != nil {
+				return err
+			}
+			merging[id] = true
+			res.Books.Updated++
+		default:
+			res.Books.Skipped++
+			continue
+		}
+		local[b.ID] = id
+	}
+
+	for _, s := range ar.Sessions {
+		bookID, ok := local[s.BookID]
+		if !ok {
+			res.Sessions.Skipped++
+			continue
+		}
+		if merging[bookID] {
+			var dup int64
+			err := tx.QueryRow(ctx, `SELECT id FROM sessions WHERE book_id = ? AND device_id = ? AND started_at = ? ORDER BY id LIMIT 1`,
+				bookID, s.DeviceID, s.StartedAt).Scan(&dup)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+			if dup != 0 {
+				localSessions[s.ID] = dup
+				res.Sessions.Skipped++
+				continue
+			}
+		}
+		// A device has at most one open session; keep the one already here.
+		if s.EndedAt == nil {
+			openID, err := openSessionIDByDevice(ctx, tx, uid, s.DeviceID)
+			if err != nil {
+				return err
+			}
+			if openID != 0 {
+				res.Sessions.Skipped++
+				continue
+			}
+		}
+		id, err := insertImportedSession(ctx, tx, uid, bookID, s.DeviceID, s.StartPage, s.EndPage, s.StartedAt, s.EndedAt, s.DurationSeconds, s.CreatedAt)
+		if err != nil {
+			return err
+		}
+		localSessions[s.ID] = id
+		res.Sessions.Created++
+	}
+
+	for _, n := range ar.Notes {
+		bookID, ok := local[n.BookID]
+		if !ok {
+			res.Notes.Skipped++
+			continue
+		}
+		var sessionID *int64
+		if n.SessionID != nil {
+			if id, ok := localSessions[*n.SessionID]; ok {
+				sessionID = &id
+			}
+		}
+		if merging[bookID] {
+			var dup int
+			if err := tx.QueryRow(ctx, `
+				SELECT COUNT(*) FROM notes
+				WHERE book_id = ? AND kind = ? AND text = ?
+					AND COALESCE(page, -1) = COALESCE(?, -1)
+					AND COALESCE(location_start, -1) = COALESCE(?, -1)
+			`, bookID, n.Kind, n.Text, n.Page, n.LocationStart).Scan(&dup); err != nil {
+				return err
+			}
+			if dup > 0 {
+				res.Notes.Skipped++
+				continue
+			}
+		}
+		if err := insertImportedNote(ctx, tx, uid, bookID, sessionID, n); err != nil {
+			return err
+		}
+		res.Notes.Created++
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type importResponse struct {
+	Books, Sessions, Devices, Goals, Notes struct {
+		Created, Updated, Skipped int
+	}
+}
+
+func exportArchive(t *testing.T, r http.Handler) map[string]any {
+	t.Helper()
+	w := doJSON(t, r, http.MethodGet, "/v1/export/archive", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("export expected 200, got %d body=%s", w.Code, w.Body.String())
+	}
+	var ar map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &ar); err != nil {
+		t.Fatalf("decode archive: %v", err)
+	}
+	return ar
+}
+
+func importArchive(t *testing.T, r http.Handler, conflict string, ar any) importResponse {
+	t.Helper()
+	w := doJSON(t, r, http.MethodPost, "/v1/import/archive?conflict="+conflict, ar)
+	if w.Code != http.StatusOK {
+		t.Fatalf("import expected 200, got %d body=%s", w.Code, w.Body.String())
+	}
+	var res importResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatalf("decode import: %v", err)
+	}
+	return res
+}
+
+func TestArchive_RoundTripIntoEmptyServer(t *testing.T) {
+	src := newTestServer(t)
+	doJSON(t, src, http.MethodPost, "/v1/devices", map[string]any{"device_id": "ipad", "name": "iPad", "type": "ipad", "timezone": "Europe/Berlin"})
+	doJSON(t, src, http.MethodPost, "/v1/goals", map[string]any{"kind": "books_per_year", "target": 12})
+	readSession(t, src, "ipad", "Dune", 0, 10, "2025-09-01T10:00:00Z", "2025-09-01T10:30:00Z")
+	readSession(t, src, "ipad", "Emma", 0, 5, "2025-09-02T10:00:00Z", "2025-09-02T10:10:00Z")
+	doJSON(t, src, http.MethodPost, "/v1/session/start", map[string]any{"device_id": "ipad", "book_title": "Dune", "start_page": 10, "started_at": "2025-09-03T10:00:00Z"})
+
+	ar := exportArchive(t, src)
+	if ar["format"] != "booksmart-archive" || ar["version"] != float64(2) || ar["user"] != "default" {
+		t.Fatalf("unexpected archive header: format=%v version=%v user=%v", ar["format"], ar["version"], ar["user"])
+	}
+	if n := len(ar["sessions"].([]any)); n != 3 {
+		t.Fatalf("expected 3 sessions in the archive, got %d", n)
+	}
+
+	dst := newTestServer(t)
+	// Take up the ids the archive uses, so its ids have to be remapped.
+	readSession(t, dst, "kindle", "Middlemarch", 0, 50, "2025-08-01T10:00:00Z", "2025-08-01T11:00:00Z")
+
+	res := importArchive(t, dst, "", ar)
+	if res.Books.Created != 2 || res.Sessions.Created != 3 || res.Devices.Created != 1 || res.Goals.Created != 1 {
+		t.Fatalf("unexpected import counts %+v", res)
+	}
+
+	w := doJSON(t, dst, http.MethodGet, "/v1/sessions?book_title=Dune&sort=started_at&order=asc", nil)
+	var list struct {
+		Items []struct {
+			BookTitle string `json:"book_title"`
+			EndPage   *int   `json:"end_page"`
+			Status    string `json:"status"`
+		} `json:"items"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &list)
+	if len(list.Items) != 2 || list.Items[0].EndPage == nil || *list.Items[0].EndPage != 10 || list.Items[1].Status != "open" {
+		t.Fatalf("Dune sessions not imported intact: %s", w.Body.String())
+	}
+	if w := doJSON(t, dst, http.MethodGet, "/v1/sessions/open?device_id=ipad", nil); w.Code != http.StatusOK {
+		t.Fatalf("imported open session should be open, got %d", w.Code)
+	}
+
+	// Importing the same archive again changes nothing.
+	res = importArchive(t, dst, "skip", ar)
+	if res.Books.Skipped != 2 || res.Sessions.Skipped != 3 || res.Books.Created+res.Sessions.Created != 0 {
+		t.Fatalf("re-import should skip everything, got %+v", res)
+	}
+}
+
+func TestArchive_ConflictPolicies(t *testing.T) {
+	src := newTestServer(t)
+	doJSON(t, src, http.MethodPost, "/v1/session/start", map[string]any{"device_id": "ipad", "book_title": "Dune", "author": "Frank Herbert", "start_page": 0, "started_at": "2025-09-01T10:00:00Z"})
+	doJSON(t, src, http.MethodPost, "/v1/session/stop", map[string]any{"device_id": "ipad", "end_page": 10, "ended_at": "2025-09-01T10:30:00Z"})
+	readSession(t, src, "ipad", "Dune", 10, 20, "2025-09-02T10:00:00Z", "2025-09-02T10:30:00Z")
+	ar := exportArchive(t, src)
+
+	book := func(r http.Handler) (author any, sessions int) {
+		w := doJSON(t, r, http.MethodGet, "/v1/books/1", nil)
+		var b map[string]any
+		_ = json.Unmarshal(w.Body.Bytes(), &b)
+		w = doJSON(t, r, http.MethodGet, "/v1/sessions?book_title=Dune", nil)
+		var list struct {
+			Items []any `json:"items"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &list)
+		return b["author"], len(list.Items)
+	}
+	// The target already read Dune once, on the same day as the archive's
+	// first session, without an author.
+	target := func() http.Handler {
+		r := newTestServer(t)
+		readSession(t, r, "ipad", "Dune", 0, 10, "2025-09-01T10:00:00Z", "2025-09-01T10:30:00Z")
+		readSession(t, r, "ipad", "Dune", 10, 12, "2025-09-05T10:00:00Z", "2025-09-05T10:05:00Z")
+		return r
+	}
+
+	r := target()
+	if res := importArchive(t, r, "skip", ar); res.Books.Skipped != 1 || res.Sessions.Skipped != 2 {
+		t.Fatalf("skip: unexpected counts %+v", res)
+	}
+	if author, n := book(r); author != nil || n != 2 {
+		t.Fatalf("skip should leave Dune alone, got author=%v sessions=%d", author, n)
+	}
+
+	r = target()
+	if res := importArchive(t, r, "merge", ar); res.Books.Updated != 1 || res.Sessions.Created != 1 || res.Sessions.Skipped != 1 {
+		t.Fatalf("merge: unexpected counts %+v", res)
+	}
+	if author, n := book(r); author != "Frank Herbert" || n != 3 {
+		t.Fatalf("merge should fill the author and add the missing session, got author=%v sessions=%d", author, n)
+	}
+
+	r = target()
+	if res := importArchive(t, r, "overwrite", ar); res.Books.Updated != 1 || res.Sessions.Created != 2 {
+		t.Fatalf("overwrite: unexpected counts %+v", res)
+	}
+	if author, n := book(r); author != "Frank Herbert" || n != 2 {
+		t.Fatalf("overwrite should replace Dune's sessions, got author=%v sessions=%d", author, n)
+	}
+}
+
+func TestArchive_RoundTripsNotes(t *testing.T) {
+	src := newTestServer(t)
+	readSession(t, src, "ipad", "Dune", 0, 10, "2025-09-01T10:00:00Z", "2025-09-01T10:30:00Z")
+	readSession(t, src, "ipad", "Dune", 10, 20, "2025-09-02T10:00:00Z", "2025-09-02T10:30:00Z")
+	doJSON(t, src, http.MethodPost, "/v1/notes", map[string]any{"session_id": 2, "kind": "quote", "text": "Fear is the mind-killer.", "page": 12, "tags": []string{"fear", "litany"}})
+	doJSON(t, src, http.MethodPost, "/v1/notes", map[string]any{"book_id": 1, "text": "Reread the appendix."})
+	ar := exportArchive(t, src)
+	if n := len(ar["notes"].([]any)); n != 2 {
+		t.Fatalf("expected 2 notes in the archive, got %d", n)
+	}
+
+	dst := newTestServer(t)
+	// Take up the ids the archive uses, so its ids have to be remapped.
+	readSession(t, dst, "kindle", "Middlemarch", 0, 50, "2025-08-01T10:00:00Z", "2025-08-01T11:00:00Z")
+	readSession(t, dst, "kindle", "Middlemarch", 50, 80, "2025-08-02T10:00:00Z", "2025-08-02T11:00:00Z")
+
+	res := importArchive(t, dst, "", ar)
+	if res.Books.Created != 1 || res.Sessions.Created != 2 || res.Notes.Created != 2 {
+		t.Fatalf("unexpected import counts %+v", res)
+	}
+
+	notes := func() []map[string]any {
+		w := doJSON(t, dst, http.MethodGet, "/v1/books/2/notes", nil)
+		var list struct {
+			Items []map[string]any `json:"items"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &list)
+		return list.Items
+	}
+	items := notes()
+	if len(items) != 2 {
+		t.Fatalf("expected Dune's 2 notes at their new book id, got %+v", items)
+	}
+	quote := items[0]
+	if quote["text"] != "Fear is the mind-killer." || quote["session_id"] != float64(4) || quote["page"] != float64(12) {
+		t.Fatalf("quote should point at Dune's second session, now id 4: %+v", quote)
+	}
+	if tags, _ := quote["tags"].([]any); len(tags) != 2 || tags[0] != "fear" || tags[1] != "litany" {
+		t.Fatalf("quote lost its tags: %+v", quote)
+	}
+	if items[1]["session_id"] != nil {
+		t.Fatalf("book note should have no session: %+v", items[1])
+	}
+
+	// Merging the same archive again finds every note already there.
+	res = importArchive(t, dst, "merge", ar)
+	if res.Notes.Created != 0 || res.Notes.Skipped != 2 {
+		t.Fatalf("merge should skip notes it has, got %+v", res)
+	}
+	// Overwriting replaces them rather than adding copies.
+	res = importArchive(t, dst, "overwrite", ar)
+	if res.Notes.Created != 2 || len(notes()) != 2 {
+		t.Fatalf("overwrite should replace Dune's notes, got %+v", res)
+	}
+}
+
+func TestArchive_ImportRejectsInvalidArchives(t *testing.T) {
+	r := newTestServer(t)
+	cases := []struct {
+		name, conflict, body, want string
+	}{
+		{"bad policy", "replace", `{"format":"booksmart-archive","version":1}`, "invalid_value"},
+		{"wrong format", "", `{"format":"zip","version":1}`, "invalid_archive"},
+		{"future version", "", `{"format":"booksmart-archive","version":99}`, "invalid_archive"},
+		{"dangling session", "", `{"format":"booksmart-archive","version":1,"books":[{"id":1,"title":"Dune"}],
+			"sessions":[{"id":1,"book_id":2,"device_id":"ipad","start_page":0,"started_at":"2025-09-01T10:00:00Z"}]}`, "sessions[0].book_id"},
+		{"bad time", "", `{"format":"booksmart-archive","version":1,"books":[{"id":1,"title":"Dune"}],
+			"sessions":[{"id":1,"book_id":1,"device_id":"ipad","start_page":0,"started_at":"yesterday"}]}`, "sessions[0].started_at"},
+		{"note on another book's session", "", `{"format":"booksmart-archive","version":2,"books":[{"id":1,"title":"Dune"},{"id":2,"title":"Emma"}],
+			"sessions":[{"id":1,"book_id":2,"device_id":"ipad","start_page":0,"started_at":"2025-09-01T10:00:00Z"}],
+			"notes":[{"book_id":1,"session_id":1,"text":"Spice."}]}`, "notes[0].session_id"},
+		{"empty note", "", `{"format":"booksmart-archive","version":2,"books":[{"id":1,"title":"Dune"}],
+			"notes":[{"book_id":1,"text":" "}]}`, "notes[0].text"},
+	}
+	for _, tc := range cases {
+		w := doJSON(t, r, http.MethodPost, "/v1/import/archive?conflict="+tc.conflict, json.RawMessage(tc.body))
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), tc.want) {
+			t.Errorf("%s: expected 400 mentioning %q, got %d body=%s", tc.name, tc.want, w.Code, w.Body.String())
+		}
+	}
+	if w := doJSON(t, r, http.MethodGet, "/v1/books", nil); strings.Contains(w.Body.String(), "Dune") {
+		t.Fatalf("rejected archives must not import anything: %s", w.Body.String())
+	}
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+const (
+	scopeRead  = "read"
+	scopeWrite = "write"
+)
+
+// authenticate resolves the caller from an "Authorization: Bearer" token and
+// enforces its scope: read tokens may only make GET and HEAD requests.
+//
+// Until the first token is created the server runs open so it can be set up:
+// requests act as the user named in X-Booksmart-User (or the default user)
+// with write access. Once any active token exists, every request needs one.
+func (a *App) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, hasToken := bearerToken(r)
+
+		if !hasToken {
+			var active int
+			if err := a.Store.QueryRow(r.Context(), `SELECT COUNT(*) FROM api_tokens WHERE revoked_at IS NULL`).Scan(&active); err != nil {
+				serverError(w, r, err, "internal error")
+				return
+			}
+			if active > 0 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="booksmart"`)
+				writeError(w, r, ErrAuthRequired)
+				return
+			}
+			a.openAccess(w, r, next)
+			return
+		}
+
+		var tokenID, uid int64
+		var scope string
+		var device *string
+		err := a.Store.QueryRow(r.Context(), `
+			SELECT id, user_id, scope, device_id
+			FROM api_tokens
+			WHERE token_hash = ? AND revoked_at IS NULL
+		`, hashToken(raw)).Scan(&tokenID, &uid, &scope, &device)
+		if errors.Is(err, sql.ErrNoRows) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="booksmart", error="invalid_token"`)
+			writeError(w, r, ErrInvalidToken)
+			return
+		}
+		if err != nil {
+			serverError(w, r, err, "internal error")
+			return
+		}
+		if scope != scopeWrite && r.Method != http.MethodGet && r.Method != http.MethodHead {
+			writeError(w, r, ErrReadOnlyToken)
+			return
+		}
+
+		_, _ = a.Store.Exec(r.Context(), `UPDATE api_tokens SET last_used_at = ? WHERE id = ?`, timeOrNowRFC3339(nil), tokenID)
+
+		logUser(r.Context(), uid)
+		ctx := withUserID(r.Context(), uid)
+		if device != nil {
+			ctx = context.WithValue(ctx, deviceCtxKey, *device)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// openAccess serves a request while no tokens exist, acting as the user named
+// in the X-Booksmart-User header or the default user when it is absent.
+func (a *App) openAccess(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	uid := defaultUserID
+	if name := strings.TrimSpace(r.Header.Get(userHeader)); name != "" {
+		err := a.Store.QueryRow(r.Context(), `SELECT id FROM users WHERE name = ?`, name).Scan(&uid)
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, r, ErrUnknownUser)
+			return
+		}
+		if err != nil {
+			serverError(w, r, err, "internal error")
+			return
+		}
+	}
+	logUser(r.Context(), uid)
+	next.ServeHTTP(w, r.WithContext(withUserID(r.Context(), uid)))
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	h := r.Header.Get("Authorization")
+	if h == "" {
+		return "", false
+	}
+	scheme, tok, ok := strings.Cut(h, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", true
+	}
+	return strings.TrimSpace(tok), true
+}
+
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,103 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// withToken sends a JSON request with a bearer token.
+func withToken(t *testing.T, r http.Handler, token, method, path string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var b []byte
+	if body != nil {
+		b, _ = json.Marshal(body)
+	}
+	req := httptest.NewRequest(method, path, bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func createToken(t *testing.T, r http.Handler, scope string) string {
+	t.Helper()
+
+	w := doJSON(t, r, http.MethodPost, "/v1/tokens", map[string]any{"name": "shortcut-" + scope, "scope": scope})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create token expected 201, got %d body=%s", w.Code, w.Body.String())
+	}
+	var resp map[string]any
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	token, _ := resp["token"].(string)
+	if !strings.HasPrefix(token, "bsm_") {
+		t.Fatalf("unexpected token: %q", token)
+	}
+	return token
+}
+
+func TestAuth_FirstTokenLocksServer(t *testing.T) {
+	r := newTestServer(t)
+
+	token := createToken(t, r, "write")
+
+	if w := doJSON(t, r, http.MethodGet, "/v1/books", nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("anonymous expected 401, got %d", w.Code)
+	}
+	if w := doJSON(t, r, http.MethodGet, "/v1/health", nil); w.Code != http.StatusOK {
+		t.Fatalf("health expected 200, got %d", w.Code)
+	}
+	if w := withToken(t, r, "bsm_not-a-real-token", http.MethodGet, "/v1/books", nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("bad token expected 401, got %d", w.Code)
+	}
+	w := withToken(t, r, token, http.MethodPost, "/v1/session/start", map[string]any{
+		"device_id":  "phone",
+		"book_title": "Dune",
+		"start_page": 1,
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("start with token expected 201, got %d body=%s", w.Code, w.Body.String())
+	}
+
+	w = withToken(t, r, token, http.MethodGet, "/v1/tokens", nil)
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), token) {
+		t.Fatalf("list must not leak the token: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAuth_ReadScopeAndRevoke(t *testing.T) {
+	r := newTestServer(t)
+
+	admin := createToken(t, r, "write")
+	w := withToken(t, r, admin, http.MethodPost, "/v1/tokens", map[string]any{"name": "dashboard", "scope": "read"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create read token expected 201, got %d body=%s", w.Code, w.Body.String())
+	}
+	var created map[string]any
+	_ = json.Unmarshal(w.Body.Bytes(), &created)
+	reader := created["token"].(string)
+
+	if w := withToken(t, r, reader, http.MethodGet, "/v1/books", nil); w.Code != http.StatusOK {
+		t.Fatalf("read token GET expected 200, got %d", w.Code)
+	}
+	w = withToken(t, r, reader, http.MethodPost, "/v1/session/start", map[string]any{
+		"device_id":  "phone",
+		"book_title": "Dune",
+	})
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("read token POST expected 403, got %d", w.Code)
+	}
+
+	w = withToken(t, r, admin, http.MethodDelete, "/v1/tokens/2", nil)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("revoke expected 204, got %d body=%s", w.Code, w.Body.String())
+	}
+	if w := withToken(t, r, reader, http.MethodGet, "/v1/books", nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("revoked token expected 401, got %d", w.Code)
+	}
+}
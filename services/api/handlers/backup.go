@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"time"
+
+	"modernc.org/sqlite"
+)
+
+const (
+	// backupPrefix starts the names of scheduled backups, the only files
+	// in the backup directory that retention deletes. Snapshots taken
+	// before a restore start with preRestorePrefix and are kept.
+	backupPrefix     = "booksmart-"
+	preRestorePrefix = "pre-restore-"
+
+	// backupTimeFormat sorts lexically in time order.
+	backupTimeFormat = "20060102T150405Z"
+
+	// restoreMaxBytes caps uploaded snapshots.
+	restoreMaxBytes = 1 << 30
+
+	// backupWindow is how long a snapshot may take to upload or download.
+	backupWindow = 10 * time.Minute
+)
+
+// sqliteMagic opens every SQLite database file.
+var sqliteMagic = []byte("SQLite format 3\x00")
+
+func (s *sqlStore) Backup(ctx context.Context, path string) error {
+	if s.dialect != dialectSQLite {
+		return ErrNotSupported.WithMessage("backups are SQLite only; use pg_dump for PostgreSQL")
+	}
+	// VACUUM INTO reads in a single transaction, so the copy is consistent
+	// while writers carry on against the WAL.
+	_, err := s.db.ExecContext(ctx, `VACUUM INTO ?`, path)
+	return err
+}
+
+func (s *sqlStore) Restore(ctx context.Context, path string) error {
+	if s.dialect != dialectSQLite {
+		return ErrNotSupported.WithMessage("restores are SQLite only; use pg_restore for PostgreSQL")
+	}
+	if err := prepareSnapshot(path); err != nil {
+		return err
+	}
+
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// The backup API copies the snapshot over the live database inside one
+	// write transaction, so other connections keep working and see the old
+	// data until it commits and the new data after.
+	return conn.Raw(func(dc any) error {
+		rc, ok := dc.(interface {
+			NewRestore(string) (*sqlite.Backup, error)
+		})
+		if !ok {
+			return errors.New("sqlite driver cannot restore")
+		}
+		b, err := rc.NewRestore("file:" + path)
+		if err != nil {
+			return err
+		}
+		for {
+			more, err := b.Step(-1)
+			if err != nil {
+				_ = b.Finish()
+				return err
+			}
+			if !more {
+				return b.Finish()
+			}
+		}
+	})
+}
+
+// prepareSnapshot checks that the SQLite file at path is an intact Booksmart
+// database no newer than this binary, and migrates it to the current schema.
+func prepareSnapshot(path string) error {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(3000)")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	var check string
+	if err := db.QueryRow(`PRAGMA integrity_check`).Scan(&check); err != nil || check != "ok" {
+		return fmt.Errorf("%w: integrity check: %s %v", ErrInvalidSnapshot, check, err)
+	}
+	var tables int
+	err = db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name IN ('books', 'sessions')`).Scan(&tables)
+	if err != nil || tables != 2 {
+		return fmt.Errorf("%w: books and sessions tables missing", ErrInvalidSnapshot)
+	}
+	if err := Migrate(db); err != nil {
+		if errors.Is(err, ErrSchemaTooNew) {
+			return fmt.Errorf("%w: %v", ErrSnapshotTooNew, err)
+		}
+		return fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+	}
+	// Leave a single self-contained file behind for the backup API.
+	_, err = db.Exec(`PRAGMA journal_mode = DELETE`)
+	return err
+}
+
+// writeBackup snapshots store into dir as <prefix><UTC time>.sqlite. The
+// snapshot is written under a temporary name and renamed when complete, so
+// a file with the final name is always whole.
+func writeBackup(ctx context.Context, store Store, dir, prefix string) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("make backup dir: %w", err)
+	}
+	name := prefix + time.Now().UTC().Format(backupTimeFormat) + ".sqlite"
+	path := filepath.Join(dir, name)
+	tmp := path + ".tmp"
+	_ = os.Remove(tmp)
+	if err := store.Backup(ctx, tmp); err != nil {
+		_ = os.Remove(tmp)
+		return "", err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return "", err
+	}
+	return name, nil
+}
+
+// pruneBackups deletes all but the newest keep scheduled backups in dir.
+func pruneBackups(dir string, keep int) error {
+	names, err := filepath.Glob(filepath.Join(dir, backupPrefix+"*.sqlite"))
+	if err != nil {
+		return err
+	}
+	slices.Sort(names)
+	var errs []error
+	for len(names) > keep {
+		errs = append(errs, os.Remove(names[0]))
+		names = names[1:]
+	}
+	return errors.Join(errs...)
+}
+
+// RunBackups writes a backup into BackupDir every interval, keeping the
+// newest keep, until ctx is done. Failures are logged and retried on the
+// next tick.
+func (a *App) RunBackups(ctx context.Context, interval time.Duration, keep int) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		name, err := writeBackup(ctx, a.Store, a.BackupDir, backupPrefix)
+		if err != nil {
+			a.logger().Error("scheduled backup", "error", err)
+			continue
+		}
+		if err := pruneBackups(a.BackupDir, keep); err != nil {
+			a.logger().Error("prune backups", "error", err)
+		}
+		a.logger().Info("scheduled backup", "file", name)
+	}
+}
+
+// backup answers with a fresh snapshot of the whole database, for every
+// user. Only the default user may take one.
+func (a *App) backup(w http.ResponseWriter, r *http.Request) {
+	if !isAdmin(r.Context()) {
+		writeError(w, r, ErrDefaultUserOnly.WithMessage("only the default user can back up the database"))
+		return
+	}
+
+	dir, err := os.MkdirTemp("", "booksmart-backup-")
+	if err != nil {
+		serverError(w, r, err, "internal error")
+		return
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "snapshot.sqlite")
+	if err := a.Store.Backup(r.Context(), path); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		serverError(w, r, err, "internal error")
+		return
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		serverError(w, r, err, "internal error")
+		return
+	}
+
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(backupWindow))
+	name := backupPrefix + time.Now().UTC().Format(backupTimeFormat) + ".sqlite"
+	w.Header().Set("Content-Type", "application/vnd.sqlite3")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	w.Header().Set("Content-Length", strconv.FormatInt(fi.Size(), 10))
+	if _, err := io.Copy(w, f); err != nil {
+		logError(r.Context(), err)
+	}
+}
+
+// restore replaces the database with the SQLite snapshot in the request
+// body, as taken by backup. The snapshot is checked and migrated before
+// anything is replaced; with a BackupDir the current database is saved
+// there first. Tokens come from the snapshot too, so the caller's token
+// may stop working.
+func (a *App) restore(w http.ResponseWriter, r *http.Request) {
+	if !isAdmin(r.Context()) {
+		writeError(w, r, ErrDefaultUserOnly.WithMessage("only the default user can restore the database"))
+		return
+	}
+	if a.Store.Dialect() != dialectSQLite {
+		writeError(w, r, ErrNotSupported.WithMessage("restores are SQLite only; use pg_restore for PostgreSQL"))
+		return
+	}
+
+	_ = http.NewResponseController(w).SetReadDeadline(time.Now().Add(backupWindow))
+	if a.BackupDir != "" {
+		if err := os.MkdirAll(a.BackupDir, 0o755); err != nil {
+			serverError(w, r, err, "internal error")
+			return
+		}
+	}
+	tmp, err := os.CreateTemp(a.BackupDir, "restore-*.sqlite")
+	if err != nil {
+		serverError(w, r, err, "internal error")
+		return
+	}
+	defer os.Remove(tmp.Name())
+	_, err = io.Copy(tmp, http.MaxBytesReader(w, r.Body, restoreMaxBytes))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeError(w, r, ErrTooLarge.WithMessage(fmt.Sprintf("snapshots are limited to %d bytes", restoreMaxBytes)))
+		return
+	}
+	if err != nil {
+		serverError(w, r, err, "read failed")
+		return
+	}
+	if !hasSQLiteMagic(tmp.Name()) {
+		writeError(w, r, ErrInvalidSnapshot)
+		return
+	}
+
+	out := map[string]any{"schema_version": SchemaVersion()}
+	if a.BackupDir != "" {
+		name, err := writeBackup(r.Context(), a.Store, a.BackupDir, preRestorePrefix)
+		if err != nil {
+			serverError(w, r, err, "pre-restore backup failed")
+			return
+		}
+		out["pre_restore_backup"] = name
+	}
+	if err := a.Store.Restore(r.Context(), tmp.Name()); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, out)
+}
+
+func hasSQLiteMagic(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	b := make([]byte, len(sqliteMagic))
+	_, err = io.ReadFull(f, b)
+	return err == nil && bytes.Equal(b, sqliteMagic)
+}
@@ -0,0 +1,127 @@
+package handlers_test
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mk-slmn/booksmart/services/api/handlers"
+)
+
+// sqliteServer returns a server on a fresh store, skipping the test when the
+// suite runs against PostgreSQL.
+func sqliteServer(t *testing.T, backupDir string) http.Handler {
+	t.Helper()
+	store := newTestDB(t)
+	if store.Dialect() != "sqlite" {
+		t.Skip("backups are SQLite only")
+	}
+	return (&handlers.App{Store: store, Logger: quietLogger, BackupDir: backupDir}).Routes()
+}
+
+func postSnapshot(t *testing.T, r http.Handler, snapshot []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/v1/admin/restore", bytes.NewReader(snapshot))
+	req.Header.Set("Content-Type", "application/vnd.sqlite3")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func countSessions(t *testing.T, r http.Handler) int {
+	t.Helper()
+	w := doJSON(t, r, http.MethodGet, "/v1/sessions", nil)
+	var resp struct {
+		Items []json.RawMessage `json:"items"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode sessions: %v body=%s", err, w.Body.String())
+	}
+	return len(resp.Items)
+}
+
+func TestBackup_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	r := sqliteServer(t, dir)
+	readSession(t, r, "ipad", "Dune", 0, 10, "2025-09-01T10:00:00Z", "2025-09-01T10:30:00Z")
+
+	w := doJSON(t, r, http.MethodPost, "/v1/admin/backup", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("backup expected 200, got %d body=%s", w.Code, w.Body.String())
+	}
+	if cd := w.Header().Get("Content-Disposition"); !strings.Contains(cd, "booksmart-") {
+		t.Fatalf("unexpected Content-Disposition %q", cd)
+	}
+	snapshot := w.Body.Bytes()
+	if !bytes.HasPrefix(snapshot, []byte("SQLite format 3\x00")) {
+		t.Fatalf("backup is not a SQLite file")
+	}
+
+	readSession(t, r, "ipad", "Emma", 0, 5, "2025-09-02T10:00:00Z", "2025-09-02T10:10:00Z")
+	if n := countSessions(t, r); n != 2 {
+		t.Fatalf("expected 2 sessions before restore, got %d", n)
+	}
+
+	w = postSnapshot(t, r, snapshot)
+	if w.Code != http.StatusOK {
+		t.Fatalf("restore expected 200, got %d body=%s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		SchemaVersion    int    `json:"schema_version"`
+		PreRestoreBackup string `json:"pre_restore_backup"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode restore: %v", err)
+	}
+	if resp.SchemaVersion != handlers.SchemaVersion() {
+		t.Fatalf("expected schema version %d, got %d", handlers.SchemaVersion(), resp.SchemaVersion)
+	}
+	if n := countSessions(t, r); n != 1 {
+		t.Fatalf("expected the snapshot's 1 session after restore, got %d", n)
+	}
+	if _, err := os.Stat(filepath.Join(dir, resp.PreRestoreBackup)); resp.PreRestoreBackup == "" || err != nil {
+		t.Fatalf("expected a pre-restore backup in %s, got %q (%v)", dir, resp.PreRestoreBackup, err)
+	}
+}
+
+func TestBackup_RestoreRejectsBadSnapshots(t *testing.T) {
+	r := sqliteServer(t, "")
+	readSession(t, r, "ipad", "Dune", 0, 10, "2025-09-01T10:00:00Z", "2025-09-01T10:30:00Z")
+
+	w := postSnapshot(t, r, []byte("definitely not a database"))
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "invalid_snapshot") {
+		t.Fatalf("expected 400 invalid_snapshot, got %d body=%s", w.Code, w.Body.String())
+	}
+
+	// A snapshot from a newer server.
+	path := filepath.Join(t.TempDir(), "new.sqlite")
+	if err := os.WriteFile(path, doJSON(t, r, http.MethodPost, "/v1/admin/backup", nil).Body.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite", "file:"+path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (9999, 'future', '2030-01-01T00:00:00Z')`); err != nil {
+		t.Fatalf("bump schema version: %v", err)
+	}
+	_ = db.Close()
+	newer, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w = postSnapshot(t, r, newer)
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), "snapshot_too_new") {
+		t.Fatalf("expected 409 snapshot_too_new, got %d body=%s", w.Code, w.Body.String())
+	}
+
+	if n := countSessions(t, r); n != 1 {
+		t.Fatalf("rejected snapshots must leave the database alone, got %d sessions", n)
+	}
+}
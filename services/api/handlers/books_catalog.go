@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"math"
+	"strings"
+	"time"
+)
+
+// Read statuses, as kept in books.read_status.
+const (
+	readStatusToRead  = "to_read"
+	readStatusReading = "reading"
+	readStatusRead    = "read"
+)
+
+// bookCatalog is a book's catalog details beyond title, author and pages.
+// Nil fields are unknown. Shelves is kept comma-separated in the database
+// and returned as a list.
+type bookCatalog struct {
+	ISBN       *string  `json:"isbn,omitempty"`
+	ReadStatus *string  `json:"read_status,omitempty"`
+	Shelves    []string `json:"shelves,omitempty"`
+	AddedOn    *string  `json:"added_on,omitempty"`    // YYYY-MM-DD
+	FinishedOn *string  `json:"finished_on,omitempty"` // YYYY-MM-DD
+
+	// AssetID is the book's Apple Books asset id; ReadingProgress (0-100)
+	// and LastOpenedAt are what Apple Books last reported.
+	AssetID         *string  `json:"asset_id,omitempty"`
+	ReadingProgress *float64 `json:"reading_progress,omitempty"`
+	LastOpenedAt    *string  `json:"last_opened_at,omitempty"`
+
+	// Rating is 0.5-5 stars in half-star steps.
+	Rating     *float64 `json:"rating,omitempty"`
+	Review     *string  `json:"review,omitempty"`
+	ReviewedAt *string  `json:"reviewed_at,omitempty"`
+}
+
+// bookCatalogFields are the columns behind bookCatalog, in the order of
+// scanFields and args.
+var bookCatalogFields = []string{
+	"isbn", "read_status", "shelves", "added_on", "finished_on",
+	"asset_id", "reading_progress", "last_opened_at",
+	"rating", "review", "reviewed_at",
+}
+
+var bookCatalogColumns = strings.Join(bookCatalogFields, ", ")
+
+// scanFields returns Scan destinations for bookCatalogColumns and a func
+// to call after Scan, which fills in Shelves.
+func (c *bookCatalog) scanFields() ([]any, func()) {
+	var shelves *string
+	fields := []any{
+		&c.ISBN, &c.ReadStatus, &shelves, &c.AddedOn, &c.FinishedOn,
+		&c.AssetID, &c.ReadingProgress, &c.LastOpenedAt,
+		&c.Rating, &c.Review, &c.ReviewedAt,
+	}
+	return fields, func() { c.Shelves = splitList(shelves) }
+}
+
+// args returns c as values for bookCatalogColumns.
+func (c bookCatalog) args() []any {
+	return []any{
+		c.ISBN, c.ReadStatus, joinList(c.Shelves), c.AddedOn, c.FinishedOn,
+		c.AssetID, c.ReadingProgress, c.LastOpenedAt,
+		c.Rating, c.Review, c.ReviewedAt,
+	}
+}
+
+// validate checks c's values as they come from an archive, blaming fields
+// under prefix.
+func (c bookCatalog) validate(prefix string) error {
+	if c.ReadStatus != nil {
+		switch *c.ReadStatus {
+		case readStatusToRead, readStatusReading, readStatusRead:
+		default:
+			return ErrInvalidArchive.Field(prefix+"read_status", "read_status must be to_read, reading or read")
+		}
+	}
+	for name, d := range map[string]*string{"added_on": c.AddedOn, "finished_on": c.FinishedOn} {
+		if d == nil {
+			continue
+		}
+		if _, err := time.Parse(time.DateOnly, *d); err != nil {
+			return ErrInvalidArchive.Field(prefix+name, name+" must be YYYY-MM-DD")
+		}
+	}
+	if c.ReadingProgress != nil && (*c.ReadingProgress < 0 || *c.ReadingProgress > 100) {
+		return ErrInvalidArchive.Field(prefix+"reading_progress", "reading_progress must be between 0 and 100")
+	}
+	for name, t := range map[string]*string{"last_opened_at": c.LastOpenedAt, "reviewed_at": c.ReviewedAt} {
+		if t == nil {
+			continue
+		}
+		if _, err := parseRFC3339UTC(*t); err != nil {
+			return ErrInvalidArchive.Field(prefix+name, name+" must be RFC3339")
+		}
+	}
+	if c.Rating != nil && !validRating(*c.Rating) {
+		return ErrInvalidArchive.Field(prefix+"rating", ratingMessage)
+	}
+	for _, s := range c.Shelves {
+		if s == "" || strings.Contains(s, ",") {
+			return ErrInvalidArchive.Field(prefix+"shelves", "shelf names must be non-empty and contain no commas")
+		}
+	}
+	return nil
+}
+
+const ratingMessage = "rating must be 0.5 to 5 in steps of 0.5"
+
+// validRating reports whether r is a number of stars from 0.5 to 5 in
+// half-star steps.
+func validRating(r float64) bool {
+	return r >= 0.5 && r <= 5 && r*2 == math.Round(r*2)
+}
+
+// splitList and joinList convert the comma-separated lists kept in the
+// database, shelves and tags, to and from slices.
+func splitList(s *string) []string {
+	if s == nil || *s == "" {
+		return nil
+	}
+	return strings.Split(*s, ",")
+}
+
+func joinList(items []string) *string {
+	if len(items) == 0 {
+		return nil
+	}
+	s := strings.Join(items, ",")
+	return &s
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+)
+
+type bookDetail struct {
+	ID             int64     `json:"id"`
+	Title          string    `json:"title"`
+	Author         *string   `json:"author,omitempty"`
+	Source         *string   `json:"source,omitempty"`
+	TotalPages     *int      `json:"total_pages,omitempty"`
+	CreatedAt      string    `json:"created_at"`
+	Sessions       int       `json:"sessions"`
+	SecondsRead    int64     `json:"seconds_read"`
+	CurrentPage    *int      `json:"current_page,omitempty"`
+	PagesRemaining *int      `json:"pages_remaining,omitempty"`
+	LastActivity   *string   `json:"last_activity,omitempty"`
+	Speed          *speed    `json:"speed,omitempty"`
+	Forecast       *forecast `json:"forecast,omitempty"`
+
+	bookCatalog
+}
+
+type speed struct {
+	PagesPerHour             float64 `json:"pages_per_hour"`
+	Basis                    string  `json:"basis"` // "book" or "overall"
+	EstimatedSecondsToFinish *int64  `json:"estimated_seconds_to_finish,omitempty"`
+}
+
+func (a *App) getBook(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	uid := userID(r.Context())
+	var out bookDetail
+	catalog, scanned := out.scanFields()
+	err = a.Store.QueryRow(r.Context(), `
+SELECT id, title, author, source, total_pages, created_at, `+bookCatalogColumns+`
+FROM books
+WHERE id = ? AND user_id = ?`, id, uid).Scan(append([]any{&out.ID, &out.Title, &out.Author, &out.Source, &out.TotalPages, &out.CreatedAt}, catalog...)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, r, ErrBookNotFound)
+		return
+	}
+	if err != nil {
+		serverError(w, r, err, "query failed")
+		return
+	}
+	scanned()
+
+	err = a.Store.QueryRow(r.Context(), `
+SELECT COUNT(*), COALESCE(SUM(duration_seconds), 0)
+FROM sessions
+WHERE book_id = ?`, id).Scan(&out.Sessions, &out.SecondsRead)
+	if err != nil {
+		serverError(w, r, err, "query failed")
+		return
+	}
+
+	var lastPage *int
+	var lastActivity string
+	err = a.Store.QueryRow(r.Context(), `
+SELECT COALESCE(end_page, start_page), COALESCE(ended_at, started_at) AS last_activity
+FROM sessions
+WHERE book_id = ?
+ORDER BY last_activity DESC, id DESC
+LIMIT 1`, id).Scan(&lastPage, &lastActivity)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		serverError(w, r, err, "query failed")
+		return
+	default:
+		out.CurrentPage = lastPage
+		out.LastActivity = &lastActivity
+	}
+
+	remaining := 0
+	if out.TotalPages != nil {
+		if out.CurrentPage != nil {
+			remaining = max(*out.TotalPages-*out.CurrentPage, 0)
+		} else {
+			remaining = *out.TotalPages
+		}
+		out.PagesRemaining = &remaining
+	}
+
+	sp, err := bookSpeedFor(r.Context(), a.Store, uid, id)
+	if err != nil {
+		serverError(w, r, err, "query failed")
+		return
+	}
+	if sp != nil && out.PagesRemaining != nil {
+		sp.EstimatedSecondsToFinish = secondsToFinish(remaining, sp.PagesPerHour)
+	}
+	out.Speed = sp
+
+	if remaining > 0 {
+		pace, err := loadReadingPace(r.Context(), a.Store, uid, time.Now(), forecastWindowDays)
+		if err != nil {
+			serverError(w, r, err, "query failed")
+			return
+		}
+		out.Forecast = forecastFinish(pace, remaining, sp)
+	}
+
+	writeJSON(w, http.StatusOK, out)
+}
+
+// bookSpeedFor returns the reading speed for a book, falling back to the
+// overall speed when the book has no usable sessions yet.
+func bookSpeedFor(ctx context.Context, q Querier, userID, bookID int64) (*speed, error) {
+	samples, err := loadSpeedSamples(ctx, q, userID, "")
+	if err != nil {
+		return nil, err
+	}
+	return bookSpeedFrom(samples, bookID), nil
+}
+
+func bookSpeedFrom(samples []speedSample, bookID int64) *speed {
+	var book, overall speedStat
+	for _, x := range samples {
+		overall.add(x)
+		if x.BookID == bookID {
+			book.add(x)
+		}
+	}
+	switch {
+	case book.PagesPerHour != nil && *book.PagesPerHour > 0:
+		return &speed{PagesPerHour: *book.PagesPerHour, Basis: "book"}
+	case overall.PagesPerHour != nil && *overall.PagesPerHour > 0:
+		return &speed{PagesPerHour: *overall.PagesPerHour, Basis: "overall"}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestGetBook_EstimatesTimeToFinish(t *testing.T) {
+	r := newTestServer(t)
+
+	w := doJSON(t, r, http.MethodPost, "/v1/session/start", map[string]any{
+		"device_id":   "phone",
+		"book_title":  "Dune",
+		"total_pages": 100,
+		"start_page":  0,
+		"started_at":  "2025-09-16T20:00:00Z",
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("start expected 201, got %d body=%s", w.Code, w.Body.String())
+	}
+
+	w = doJSON(t, r, http.MethodPost, "/v1/session/stop", map[string]any{
+		"device_id": "phone",
+		"end_page":  40,
+		"ended_at":  "2025-09-16T21:00:00Z",
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("stop expected 200, got %d body=%s", w.Code, w.Body.String())
+	}
+
+	w = doJSON(t, r, http.MethodGet, "/v1/books/1", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", w.Code, w.Body.String())
+	}
+
+	var resp map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if resp["pages_remaining"] != float64(60) {
+		t.Fatalf("pages_remaining mismatch: %#v", resp["pages_remaining"])
+	}
+	sp, _ := resp["speed"].(map[string]any)
+	if sp["pages_per_hour"] != float64(40) || sp["basis"] != "book" {
+		t.Fatalf("speed mismatch: %#v", resp["speed"])
+	}
+	if sp["estimated_seconds_to_finish"] != float64(90*60) {
+		t.Fatalf("estimate mismatch: %#v", sp["estimated_seconds_to_finish"])
+	}
+}
+
+func TestGetBook_404WhenMissing(t *testing.T) {
+	r := newTestServer(t)
+
+	w := doJSON(t, r, http.MethodGet, "/v1/books/42", nil)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d body=%s", w.Code, w.Body.String())
+	}
+}
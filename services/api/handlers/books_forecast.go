@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+type bookForecast struct {
+	ID          int64     `json:"id"`
+	Title       string    `json:"title"`
+	Author      *string   `json:"author,omitempty"`
+	TotalPages  int       `json:"total_pages"`
+	CurrentPage int       `json:"current_page"`
+	Forecast    *forecast `json:"forecast"`
+}
+
+func (a *App) readingForecast(w http.ResponseWriter, r *http.Request) {
+	days := forecastWindowDays
+	if v := r.URL.Query().Get("days"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			if n > 90 {
+				n = 90
+			}
+			days = n
+		}
+	}
+
+	// In progress: total_pages known, read at least once, not yet at the end.
+	const q = `
+SELECT b.id, b.title, b.author, b.total_pages,
+  (SELECT COALESCE(s.end_page, s.start_page)
+   FROM sessions s
+   WHERE s.book_id = b.id
+   ORDER BY COALESCE(s.ended_at, s.started_at) DESC, s.id DESC
+   LIMIT 1) AS current_page
+FROM books b
+WHERE b.user_id = ?
+  AND b.total_pages IS NOT NULL
+  AND EXISTS (SELECT 1 FROM sessions s WHERE s.book_id = b.id);`
+
+	uid := userID(r.Context())
+	rows, err := a.Store.Query(r.Context(), q, uid)
+	if err != nil {
+		serverError(w, r, err, "query failed")
+		return
+	}
+	defer rows.Close()
+
+	items := make([]bookForecast, 0)
+	for rows.Next() {
+		var it bookForecast
+		if err := rows.Scan(&it.ID, &it.Title, &it.Author, &it.TotalPages, &it.CurrentPage); err != nil {
+			serverError(w, r, err, "scan failed")
+			return
+		}
+		if it.CurrentPage < it.TotalPages {
+			items = append(items, it)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		serverError(w, r, err, "row error")
+		return
+	}
+
+	pace, err := loadReadingPace(r.Context(), a.Store, uid, time.Now(), days)
+	if err != nil {
+		serverError(w, r, err, "query failed")
+		return
+	}
+	samples, err := loadSpeedSamples(r.Context(), a.Store, uid, "")
+	if err != nil {
+		serverError(w, r, err, "query failed")
+		return
+	}
+	for i := range items {
+		sp := bookSpeedFrom(samples, items[i].ID)
+		items[i].Forecast = forecastFinish(pace, items[i].TotalPages-items[i].CurrentPage, sp)
+	}
+
+	// Soonest finish first; books without an estimate go last.
+	sort.SliceStable(items, func(i, j int) bool {
+		di, dj := estimatedDays(items[i].Forecast), estimatedDays(items[j].Forecast)
+		return di < dj
+	})
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"items": items,
+		"meta":  map[string]any{"window_days": days, "count": len(items)},
+	})
+}
+
+func estimatedDays(f *forecast) float64 {
+	if f == nil || f.EstimatedDays == nil {
+		return math.Inf(1)
+	}
+	return *f.EstimatedDays
+}
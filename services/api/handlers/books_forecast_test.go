@@ -0,0 +1,144 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestReadingForecast_ListsInProgressBooks(t *testing.T) {
+	r := newTestServer(t)
+
+	day := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -2).Add(20 * time.Hour)
+	at := func(d time.Time) string { return d.Format(time.RFC3339) }
+
+	w := doJSON(t, r, http.MethodPost, "/v1/session/start", map[string]any{
+		"device_id":   "phone",
+		"book_title":  "Dune",
+		"total_pages": 200,
+		"start_page":  0,
+		"started_at":  at(day),
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("start expected 201, got %d body=%s", w.Code, w.Body.String())
+	}
+	w = doJSON(t, r, http.MethodPost, "/v1/session/stop", map[string]any{
+		"device_id": "phone",
+		"end_page":  30,
+		"ended_at":  at(day.Add(time.Hour)),
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("stop expected 200, got %d body=%s", w.Code, w.Body.String())
+	}
+	readSession(t, r, "phone", "Dune", 30, 60, at(day.AddDate(0, 0, 1)), at(day.AddDate(0, 0, 1).Add(time.Hour)))
+	// No total_pages: not forecastable.
+	readSession(t, r, "phone", "Emma", 0, 10, at(day), at(day.Add(10*time.Minute)))
+
+	w = doJSON(t, r, http.MethodGet, "/v1/books/reading/forecast", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Items []struct {
+			Title    string `json:"title"`
+			Forecast struct {
+				PagesRemaining  int     `json:"pages_remaining"`
+				PagesPerHour    float64 `json:"pages_per_hour"`
+				SpeedBasis      string  `json:"speed_basis"`
+				EstimatedFinish string  `json:"estimated_finish"`
+				EarliestFinish  string  `json:"earliest_finish"`
+			} `json:"forecast"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if len(resp.Items) != 1 || resp.Items[0].Title != "Dune" {
+		t.Fatalf("expected only Dune, got %+v", resp.Items)
+	}
+	f := resp.Items[0].Forecast
+	if f.PagesRemaining != 140 || f.SpeedBasis != "recent" {
+		t.Fatalf("unexpected forecast: %+v", f)
+	}
+	if f.EstimatedFinish == "" || f.EarliestFinish == "" || f.EarliestFinish > f.EstimatedFinish {
+		t.Fatalf("unexpected finish range: %+v", f)
+	}
+
+	w = doJSON(t, r, http.MethodGet, "/v1/books/1", nil)
+	var detail map[string]any
+	_ = json.Unmarshal(w.Body.Bytes(), &detail)
+	if detail["forecast"] == nil {
+		t.Fatalf("expected forecast in book detail: %s", w.Body.String())
+	}
+}
+
+func TestReadingForecast_RangeWidensWithDailySpread(t *testing.T) {
+	r := newTestServer(t)
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	at := func(d time.Time) string { return d.Format(time.RFC3339) }
+
+	// Six days of uneven reading at 60 pages per hour, then none today:
+	// 90, 30, 60, 0, 120, 30, 0 minutes (mean ≈ 47, stddev ≈ 45).
+	w := doJSON(t, r, http.MethodPost, "/v1/session/start", map[string]any{
+		"device_id":   "phone",
+		"book_title":  "Dune",
+		"total_pages": 1000,
+		"start_page":  0,
+		"started_at":  at(today.AddDate(0, 0, -6).Add(8 * time.Hour)),
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("start expected 201, got %d body=%s", w.Code, w.Body.String())
+	}
+	w = doJSON(t, r, http.MethodPost, "/v1/session/stop", map[string]any{
+		"device_id": "phone",
+		"end_page":  90,
+		"ended_at":  at(today.AddDate(0, 0, -6).Add(8*time.Hour + 90*time.Minute)),
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("stop expected 200, got %d body=%s", w.Code, w.Body.String())
+	}
+	page := 90
+	for i, minutes := range []int{30, 60, 0, 120, 30} {
+		if minutes == 0 {
+			continue
+		}
+		start := today.AddDate(0, 0, i-5).Add(8 * time.Hour)
+		readSession(t, r, "phone", "Dune", page, page+minutes, at(start), at(start.Add(time.Duration(minutes)*time.Minute)))
+		page += minutes
+	}
+
+	w = doJSON(t, r, http.MethodGet, "/v1/books/reading/forecast?days=7", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Items []struct {
+			Forecast struct {
+				PagesRemaining  int    `json:"pages_remaining"`
+				EstimatedFinish string `json:"estimated_finish"`
+				EarliestFinish  string `json:"earliest_finish"`
+				LatestFinish    string `json:"latest_finish"`
+			} `json:"forecast"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if len(resp.Items) != 1 {
+		t.Fatalf("expected one book, got %s", w.Body.String())
+	}
+	f := resp.Items[0].Forecast
+	if f.PagesRemaining != 670 {
+		t.Fatalf("expected 670 pages remaining, got %+v", f)
+	}
+
+	// 670 minutes to go: about 14.2 days at the mean, 10.3 days when every
+	// day runs z·stddev·√d ahead and 19.7 when it runs as far behind.
+	day := func(n int) string { return today.AddDate(0, 0, n).Format(time.DateOnly) }
+	if f.EstimatedFinish != day(15) || f.EarliestFinish != day(11) || f.LatestFinish != day(20) {
+		t.Fatalf("expected %s..%s..%s, got %+v", day(11), day(15), day(20), f)
+	}
+}
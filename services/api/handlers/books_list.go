@@ -1,106 +1,250 @@
 package handlers
 
 import (
-	"database/sql"
+	"context"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 )
 
 type bookItem struct {
-	ID        int64   `json:"id"`
-	Title     string  `json:"title"`
-	Author    *string `json:"author,omitempty"`
-	Source    *string `json:"source,omitempty"`
-	CreatedAt string  `json:"created_at"`
+	ID        int64    `json:"id"`
+	Title     string   `json:"title"`
+	Author    *string  `json:"author,omitempty"`
+	Source    *string  `json:"source,omitempty"`
+	Rating    *float64 `json:"rating,omitempty"`
+	CreatedAt string   `json:"created_at"`
+
+	sortKey string // the listing's sort value, for the next cursor
 }
 
-func (a *App) listBooks(w http.ResponseWriter, r *http.Request) {
-	limit := 50
-	if v := r.URL.Query().Get("limit"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil && n > 0 {
-			if n > 200 {
-				n = 200
+// bookFilter is the set of query parameters that narrow a book listing.
+type bookFilter struct {
+	Q                    string
+	MinRating, MaxRating *float64
+	Rated                *bool
+}
+
+// parseBookFilter reads:
+//
+//	q                        case-insensitive substring of title or author
+//	min_rating, max_rating   stars, inclusive; unrated books never match
+//	rated                    true or false
+func parseBookFilter(q url.Values) (bookFilter, error) {
+	f := bookFilter{Q: strings.TrimSpace(q.Get("q"))}
+	for _, p := range []struct {
+		name string
+		dst  **float64
+	}{{"min_rating", &f.MinRating}, {"max_rating", &f.MaxRating}} {
+		if v := strings.TrimSpace(q.Get(p.name)); v != "" {
+			n, err := strconv.ParseFloat(v, 64)
+			if err != nil || n < 0 || n > 5 {
+				return f, ErrInvalidValue.Field(p.name, p.name+" must be a number of stars from 0 to 5")
 			}
-			limit = n
+			*p.dst = &n
 		}
 	}
-	offset := 0
-	if v := r.URL.Query().Get("offset"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
-			offset = n
+	if v := strings.TrimSpace(q.Get("rated")); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return f, ErrInvalidValue.Field("rated", "rated must be true or false")
 		}
+		f.Rated = &b
 	}
-	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	return f, nil
+}
 
-	where := ""
-	args := []any{}
-	if q != "" {
-		where = "WHERE LOWER(b.title) LIKE ? OR LOWER(IFNULL(b.author,'')) LIKE ?"
-		like := "%" + strings.ToLower(q) + "%"
+// where returns the conditions selecting userID's books that match f, for a
+// query over books b.
+func (f bookFilter) where(userID int64) ([]string, []any) {
+	conds := []string{"b.user_id = ?"}
+	args := []any{userID}
+	if f.Q != "" {
+		conds = append(conds, "(LOWER(b.title) LIKE ? OR LOWER(COALESCE(b.author,'')) LIKE ?)")
+		like := "%" + strings.ToLower(f.Q) + "%"
 		args = append(args, like, like)
 	}
+	if f.MinRating != nil {
+		conds = append(conds, "b.rating >= ?")
+		args = append(args, *f.MinRating)
+	}
+	if f.MaxRating != nil {
+		conds = append(conds, "b.rating <= ?")
+		args = append(args, *f.MaxRating)
+	}
+	if f.Rated != nil {
+		if *f.Rated {
+			conds = append(conds, "b.rating IS NOT NULL")
+		} else {
+			conds = append(conds, "b.rating IS NULL")
+		}
+	}
+	return conds, args
+}
+
+// bookSort is an ordering for book listings. expr is never NULL, so it can
+// key a cursor; unrated books sort as 0 stars.
+type bookSort struct {
+	name    string
+	expr    string
+	numeric bool
+	desc    bool
+}
+
+var bookSorts = map[string]bookSort{
+	"created_at": {name: "created_at", expr: "b.created_at"},
+	"rating":     {name: "rating", expr: "COALESCE(b.rating, 0)", numeric: true},
+}
+
+// parseBookSort reads sort= (created_at or rating) and order= (asc or
+// desc, default desc).
+func parseBookSort(q url.Values) (bookSort, error) {
+	name := strings.ToLower(strings.TrimSpace(q.Get("sort")))
+	if name == "" {
+		name = "created_at"
+	}
+	s, ok := bookSorts[name]
+	if !ok {
+		return s, ErrInvalidValue.Field("sort", "sort must be created_at or rating")
+	}
+	switch strings.ToLower(strings.TrimSpace(q.Get("order"))) {
+	case "", "desc":
+		s.desc = true
+	case "asc":
+	default:
+		return s, ErrInvalidValue.Field("order", "order must be asc or desc")
+	}
+	return s, nil
+}
+
+func (s bookSort) order() string {
+	if s.desc {
+		return "desc"
+	}
+	return "asc"
+}
+
+// key identifies the ordering in cursors. The default one keeps the empty
+// key cursors had before books could be sorted.
+func (s bookSort) key() string {
+	if s.name == "created_at" && s.desc {
+		return ""
+	}
+	return s.name + ":" + s.order()
+}
+
+// orderBy is the ORDER BY clause, with the id breaking ties.
+func (s bookSort) orderBy() string {
+	dir := " " + strings.ToUpper(s.order())
+	return "ORDER BY " + s.expr + dir + ", b.id" + dir
+}
+
+// after is the condition selecting rows past c in this order.
+func (s bookSort) after(c cursor) (string, []any, error) {
+	var key any = c.Key
+	if s.numeric {
+		n, err := strconv.ParseFloat(c.Key, 64)
+		if err != nil {
+			return "", nil, errInvalidCursor
+		}
+		key = n
+	}
+	op := ">"
+	if s.desc {
+		op = "<"
+	}
+	return "(" + s.expr + " " + op + " ? OR (" + s.expr + " = ? AND b.id " + op + " ?))", []any{key, key, c.ID}, nil
+}
+
+// listBooks pages through the books matching bookFilter, newest first by
+// default, keyed on the sort value and id.
+func (a *App) listBooks(w http.ResponseWriter, r *http.Request) {
+	p, err := parsePage(r, 50, 200)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	f, err := parseBookFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	order, err := parseBookSort(r.URL.Query())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	uid := userID(r.Context())
+	conds, args := f.where(uid)
+	if p.after != nil {
+		cond, condArgs, err := order.after(*p.after)
+		if err != nil || p.after.Sort != order.key() {
+			writeError(w, r, errInvalidCursor)
+			return
+		}
+		conds = append(conds, cond)
+		args = append(args, condArgs...)
+	}
 
 	query := `
-SELECT b.id, b.title, b.author, b.source, b.created_at
+SELECT b.id, b.title, b.author, b.source, b.rating, b.created_at, ` + order.expr + ` AS sort_key
 FROM books b
-` + where + `
-ORDER BY b.created_at DESC, b.id DESC
-LIMIT ? OFFSET ?;
+WHERE ` + strings.Join(conds, " AND ") + `
+` + order.orderBy() + `
+LIMIT ?;
 `
-	argsQ := append(args, limit, offset)
-
-	rows, err := a.DB.Query(query, argsQ...)
+	rows, err := a.Store.Query(r.Context(), query, append(args, p.limit+1)...)
 	if err != nil {
-		writeErr(w, http.StatusInternalServerError, "query failed")
+		serverError(w, r, err, "query failed")
 		return
 	}
 	defer rows.Close()
 
-	items := make([]bookItem, 0, limit)
+	items := make([]bookItem, 0, p.limit+1)
 	for rows.Next() {
 		var it bookItem
-		if err := rows.Scan(&it.ID, &it.Title, &it.Author, &it.Source, &it.CreatedAt); err != nil {
-			writeErr(w, http.StatusInternalServerError, "scan failed")
+		if err := rows.Scan(&it.ID, &it.Title, &it.Author, &it.Source, &it.Rating, &it.CreatedAt, &it.sortKey); err != nil {
+			serverError(w, r, err, "scan failed")
 			return
 		}
 		items = append(items, it)
 	}
 	if err := rows.Err(); err != nil {
-		writeErr(w, http.StatusInternalServerError, "row error")
+		serverError(w, r, err, "row error")
 		return
 	}
 
-	total, err := countBooks(a.DB, q)
-	if err != nil {
-		writeErr(w, http.StatusInternalServerError, "count failed")
-		return
+	next := nextPage(w, r, p, &items, func(it bookItem) cursor {
+		return cursor{Sort: order.key(), Key: it.sortKey, ID: it.ID}
+	})
+	meta := map[string]any{
+		"limit":       p.limit,
+		"count":       len(items),
+		"next_cursor": next,
+		"q":           f.Q,
+		"sort":        order.name,
+		"order":       order.order(),
+	}
+	if p.withTotal {
+		total, err := countBooks(r.Context(), a.Store, uid, f)
+		if err != nil {
+			serverError(w, r, err, "count failed")
+			return
+		}
+		meta["total"] = total
 	}
 
 	writeJSON(w, http.StatusOK, map[string]any{
 		"items": items,
-		"meta": map[string]any{
-			"limit":  limit,
-			"offset": offset,
-			"count":  len(items),
-			"total":  total,
-			"q":      q,
-		},
+		"meta":  meta,
 	})
 }
 
-func countBooks(db *sql.DB, q string) (int, error) {
-	if strings.TrimSpace(q) == "" {
-		var n int
-		err := db.QueryRow(`SELECT COUNT(*) FROM books`).Scan(&n)
-		return n, err
-	}
-	q = "%" + strings.ToLower(strings.TrimSpace(q)) + "%"
+func countBooks(ctx context.Context, q Querier, userID int64, f bookFilter) (int, error) {
+	conds, args := f.where(userID)
 	var n int
-	err := db.QueryRow(`
-		SELECT COUNT(*)
-		FROM books b
-		WHERE LOWER(b.title) LIKE ? OR LOWER(IFNULL(b.author,'')) LIKE ?
-	`, q, q).Scan(&n)
+	err := q.QueryRow(ctx, `SELECT COUNT(*) FROM books b WHERE `+strings.Join(conds, " AND "), args...).Scan(&n)
 	return n, err
 }
@@ -0,0 +1,46 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestListBooks_CursorPagination(t *testing.T) {
+	r := newTestServer(t)
+	for _, title := range []string{"Dune", "Emma", "Ulysses"} {
+		doJSON(t, r, http.MethodPost, "/v1/session/start", map[string]any{"device_id": "ipad", "book_title": title})
+	}
+
+	var titles []string
+	path := "/v1/books?limit=2"
+	for path != "" {
+		w := doJSON(t, r, http.MethodGet, path, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s expected 200, got %d", path, w.Code)
+		}
+		var resp struct {
+			Items []struct {
+				Title string `json:"title"`
+			} `json:"items"`
+			Meta struct {
+				NextCursor *string `json:"next_cursor"`
+			} `json:"meta"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		for _, it := range resp.Items {
+			titles = append(titles, it.Title)
+		}
+		path = ""
+		if resp.Meta.NextCursor != nil {
+			path = "/v1/books?limit=2&cursor=" + *resp.Meta.NextCursor
+		}
+	}
+
+	// Books created in the same second are ordered by id, newest first.
+	if len(titles) != 3 || titles[0] != "Ulysses" || titles[1] != "Emma" || titles[2] != "Dune" {
+		t.Fatalf("unexpected order %v", titles)
+	}
+}
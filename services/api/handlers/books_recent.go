@@ -36,16 +36,16 @@ SELECT
   END AS last_activity
 FROM books b
 LEFT JOIN sessions s ON s.book_id = b.id
+WHERE b.user_id = ?
 GROUP BY b.id
 ORDER BY
-  last_activity IS NULL,  -- false first (has activity), true last (never read)
-  last_activity DESC,     -- newest first
-  b.created_at DESC       -- tie-breaker for never-read books
+  last_activity DESC NULLS LAST,  -- newest first, never-read books last
+  b.created_at DESC               -- tie-breaker for never-read books
 LIMIT ?;`
 
-	rows, err := a.DB.Query(q, limit)
+	rows, err := a.Store.Query(r.Context(), q, userID(r.Context()), limit)
 	if err != nil {
-		writeErr(w, http.StatusInternalServerError, "query failed")
+		serverError(w, r, err, "query failed")
 		return
 	}
 	defer rows.Close()
@@ -55,14 +55,14 @@ LIMIT ?;`
 		var it recentBook
 		var last *string
 		if err := rows.Scan(&it.ID, &it.Title, &it.Author, &it.Source, &last); err != nil {
-			writeErr(w, http.StatusInternalServerError, "scan failed")
+			serverError(w, r, err, "scan failed")
 			return
 		}
 		it.LastActivity = last
 		items = append(items, it)
 	}
 	if err := rows.Err(); err != nil {
-		writeErr(w, http.StatusInternalServerError, "row error")
+		serverError(w, r, err, "row error")
 		return
 	}
 
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+type reviewRequest struct {
+	Rating *float64 `json:"rating,omitempty"`
+	Review *string  `json:"review,omitempty"`
+}
+
+type reviewResponse struct {
+	BookID     int64    `json:"book_id"`
+	Rating     *float64 `json:"rating,omitempty"`
+	Review     *string  `json:"review,omitempty"`
+	ReviewedAt *string  `json:"reviewed_at,omitempty"`
+}
+
+// putReview replaces a book's rating and review. Either may be left out;
+// leaving out both removes the review.
+func (a *App) putReview(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	var req reviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, ErrInvalidJSON)
+		return
+	}
+	if req.Rating != nil && !validRating(*req.Rating) {
+		writeError(w, r, ErrInvalidValue.Field("rating", ratingMessage))
+		return
+	}
+	if req.Review != nil {
+		req.Review = optional(strings.TrimSpace(*req.Review))
+	}
+
+	out := reviewResponse{BookID: id, Rating: req.Rating, Review: req.Review}
+	if out.Rating != nil || out.Review != nil {
+		out.ReviewedAt = ptr(timeOrNowRFC3339(nil))
+	}
+	res, err := a.Store.Exec(r.Context(), `UPDATE books SET rating = ?, review = ?, reviewed_at = ? WHERE id = ? AND user_id = ?`,
+		out.Rating, out.Review, out.ReviewedAt, id, userID(r.Context()))
+	if err != nil {
+		serverError(w, r, err, "internal error")
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		writeError(w, r, ErrBookNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, out)
+}
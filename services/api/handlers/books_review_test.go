@@ -0,0 +1,154 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestBookReview(t *testing.T) {
+	r := newTestServer(t)
+	readSession(t, r, "ipad", "Dune", 0, 20, "2025-09-01T10:00:00Z", "2025-09-01T10:30:00Z")
+
+	w := doJSON(t, r, http.MethodPut, "/v1/books/1/review", map[string]any{"rating": 4.5, "review": " Spice, sand and politics. "})
+	if w.Code != http.StatusOK {
+		t.Fatalf("review expected 200, got %d body=%s", w.Code, w.Body.String())
+	}
+	var book struct {
+		Rating     *float64 `json:"rating"`
+		Review     *string  `json:"review"`
+		ReviewedAt *string  `json:"reviewed_at"`
+	}
+	_ = json.Unmarshal(doJSON(t, r, http.MethodGet, "/v1/books/1", nil).Body.Bytes(), &book)
+	if book.Rating == nil || *book.Rating != 4.5 || book.Review == nil || *book.Review != "Spice, sand and politics." || book.ReviewedAt == nil {
+		t.Fatalf("review not on the book: %+v", book)
+	}
+	if w := doJSON(t, r, http.MethodGet, "/v1/books", nil); !strings.Contains(w.Body.String(), `"rating":4.5`) {
+		t.Fatalf("rating missing from the book list: %s", w.Body.String())
+	}
+
+	// An empty review removes it.
+	doJSON(t, r, http.MethodPut, "/v1/books/1/review", map[string]any{})
+	book.Rating, book.Review, book.ReviewedAt = nil, nil, nil
+	_ = json.Unmarshal(doJSON(t, r, http.MethodGet, "/v1/books/1", nil).Body.Bytes(), &book)
+	if book.Rating != nil || book.Review != nil || book.ReviewedAt != nil {
+		t.Fatalf("review should be removed: %+v", book)
+	}
+
+	for _, tc := range []struct {
+		name, path string
+		body       map[string]any
+		status     int
+	}{
+		{"zero stars", "/v1/books/1/review", map[string]any{"rating": 0}, http.StatusBadRequest},
+		{"quarter star", "/v1/books/1/review", map[string]any{"rating": 3.25}, http.StatusBadRequest},
+		{"six stars", "/v1/books/1/review", map[string]any{"rating": 6}, http.StatusBadRequest},
+		{"unknown book", "/v1/books/9/review", map[string]any{"rating": 3}, http.StatusNotFound},
+	} {
+		if w := doJSON(t, r, http.MethodPut, tc.path, tc.body); w.Code != tc.status {
+			t.Errorf("%s: expected %d, got %d body=%s", tc.name, tc.status, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestListBooks_RatingSortAndFilter(t *testing.T) {
+	r := newTestServer(t)
+	ratings := map[string]float64{"Dune": 4, "Emma": 5, "Ulysses": 2.5}
+	for _, title := range []string{"Dune", "Emma", "Ulysses", "Middlemarch"} {
+		doJSON(t, r, http.MethodPost, "/v1/session/start", map[string]any{"device_id": "ipad", "book_title": title})
+	}
+	for i, title := range []string{"Dune", "Emma", "Ulysses"} {
+		doJSON(t, r, http.MethodPut, "/v1/books/"+strconv.Itoa(i+1)+"/review", map[string]any{"rating": ratings[title]})
+	}
+
+	titles := func(path string) (out []string, next *string) {
+		w := doJSON(t, r, http.MethodGet, path, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s expected 200, got %d body=%s", path, w.Code, w.Body.String())
+		}
+		var resp struct {
+			Items []struct {
+				Title string `json:"title"`
+			} `json:"items"`
+			Meta struct {
+				NextCursor *string `json:"next_cursor"`
+			} `json:"meta"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		for _, it := range resp.Items {
+			out = append(out, it.Title)
+		}
+		return out, resp.Meta.NextCursor
+	}
+
+	// Paging through the rating order, unrated books last.
+	var all []string
+	path := "/v1/books?sort=rating&limit=3"
+	for path != "" {
+		page, next := titles(path)
+		all = append(all, page...)
+		path = ""
+		if next != nil {
+			path = "/v1/books?sort=rating&limit=3&cursor=" + *next
+		}
+	}
+	if strings.Join(all, ",") != "Emma,Dune,Ulysses,Middlemarch" {
+		t.Fatalf("unexpected rating order %v", all)
+	}
+	if got, _ := titles("/v1/books?sort=rating&order=asc&rated=true"); strings.Join(got, ",") != "Ulysses,Dune,Emma" {
+		t.Fatalf("unexpected ascending rated order %v", got)
+	}
+	if got, _ := titles("/v1/books?min_rating=3&max_rating=4.5"); strings.Join(got, ",") != "Dune" {
+		t.Fatalf("unexpected rating range %v", got)
+	}
+	if got, _ := titles("/v1/books?rated=false"); strings.Join(got, ",") != "Middlemarch" {
+		t.Fatalf("unexpected unrated books %v", got)
+	}
+
+	// A cursor only works with the sort it was made for.
+	_, next := titles("/v1/books?sort=rating&limit=1")
+	if w := doJSON(t, r, http.MethodGet, "/v1/books?cursor="+*next, nil); w.Code != http.StatusBadRequest {
+		t.Fatalf("rating cursor with the default sort: expected 400, got %d", w.Code)
+	}
+	if w := doJSON(t, r, http.MethodGet, "/v1/books?sort=pages", nil); w.Code != http.StatusBadRequest {
+		t.Fatalf("unknown sort: expected 400, got %d", w.Code)
+	}
+}
+
+func TestStatsRatings(t *testing.T) {
+	r := newTestServer(t)
+	postGoodreads(t, r, `Title,Author,My Rating,Date Read,Exclusive Shelf
+Dune,Frank Herbert,5,2024/06/01,read
+Dune Messiah,Frank Herbert,3.5,2025/02/01,read
+Emma,Jane Austen,4,2025/03/01,read
+Ulysses,James Joyce,0,,to-read
+`)
+
+	w := doJSON(t, r, http.MethodGet, "/v1/stats/ratings", nil)
+	var stats struct {
+		RatedBooks int     `json:"rated_books"`
+		Average    float64 `json:"average"`
+		ByAuthor   []struct {
+			Author  string  `json:"author"`
+			Books   int     `json:"books"`
+			Average float64 `json:"average"`
+		} `json:"by_author"`
+		ByYear []struct {
+			Year    string  `json:"year"`
+			Books   int     `json:"books"`
+			Average float64 `json:"average"`
+		} `json:"by_year"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &stats)
+	if stats.RatedBooks != 3 || stats.Average != 4.17 {
+		t.Fatalf("unexpected overall ratings: %s", w.Body.String())
+	}
+	if len(stats.ByAuthor) != 2 || stats.ByAuthor[0].Author != "Frank Herbert" || stats.ByAuthor[0].Average != 4.25 || stats.ByAuthor[0].Books != 2 {
+		t.Fatalf("unexpected ratings by author: %s", w.Body.String())
+	}
+	if len(stats.ByYear) != 2 || stats.ByYear[0].Year != "2024" || stats.ByYear[1].Average != 3.75 || stats.ByYear[1].Books != 2 {
+		t.Fatalf("unexpected ratings by year: %s", w.Body.String())
+	}
+}
@@ -7,12 +7,13 @@ import (
 var corsMW = cors.Handler(cors.Options{
 	AllowedOrigins: []string{"*"}, // change for production
 
-	AllowedMethods: []string{"GET", "POST", "OPTIONS"},
+	AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 	AllowedHeaders: []string{
 		"Accept",
 		"Authorization",
 		"Content-Type",
 		"X-CSRF-Token",
+		"X-Booksmart-User",
 	},
 	ExposedHeaders:   []string{"Link"},
 	AllowCredentials: false,
@@ -5,12 +5,31 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
+	_ "github.com/jackc/pgx/v5/stdlib"
 	_ "modernc.org/sqlite"
 )
 
-func OpenDB() (*sql.DB, error) {
-	path := os.Getenv("SQLITE_PATH")
+// sqlitePragmas are applied by the driver to every pooled connection.
+// journal_mode is persistent, so it only has to succeed once per file.
+const sqlitePragmas = "_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)&_pragma=busy_timeout(3000)"
+
+// OpenStore connects to and migrates the database named by dsn. A
+// postgres:// or postgresql:// URL selects PostgreSQL; anything else is a
+// SQLite file path, with "" meaning ./data/reading.sqlite.
+func OpenStore(dsn string) (Store, error) {
+	if isPostgresDSN(dsn) {
+		return openPostgres(dsn)
+	}
+	return openSQLite(strings.TrimPrefix(dsn, "sqlite://"))
+}
+
+func isPostgresDSN(dsn string) bool {
+	return strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://")
+}
+
+func openSQLite(path string) (Store, error) {
 	if path == "" {
 		path = filepath.Join(".", "data", "reading.sqlite")
 	}
@@ -20,54 +39,35 @@ func OpenDB() (*sql.DB, error) {
 		}
 	}
 
-	db, err := sql.Open("sqlite", path)
+	db, err := sql.Open("sqlite", "file:"+path+"?"+sqlitePragmas)
 	if err != nil {
 		return nil, err
 	}
+	if path == ":memory:" {
+		// Every connection would otherwise get its own empty database.
+		db.SetMaxOpenConns(1)
+	}
 
-	if _, err := db.Exec(ReadSchema()); err != nil {
+	if err := Migrate(db); err != nil {
 		_ = db.Close()
 		return nil, fmt.Errorf("migrate: %w", err)
 	}
-	return db, nil
+	return NewSQLiteStore(db), nil
 }
 
-func ReadSchema() string {
-	return `	
-	PRAGMA journal_mode=WAL;
-	PRAGMA foreign_keys=ON;
-	PRAGMA busy_timeout=3000;
-
-	CREATE TABLE IF NOT EXISTS books (
-		id INTEGER PRIMARY KEY,
-		title TEXT NOT NULL UNIQUE,
-		author TEXT,
-		source TEXT,
-		created_at TEXT NOT NULL DEFAULT (datetime('now'))
-	);
-
-	CREATE TABLE IF NOT EXISTS sessions (
-		id INTEGER PRIMARY KEY, 
-		book_id INTEGER NOT NULL REFERENCES books(id),
-		device_id TEXT NOT NULL,
-		start_page INTEGER NOT NULL CHECK (start_page >= 0),
-		end_page INTEGER CHECK (end_page IS NULL OR end_page >= 0),
-		started_at TEXT NOT NULL, -- RFC3339 UTC
-		ended_at TEXT, -- RFC3339 UTC
-		duration_seconds INTEGER, -- set when stopping
-		created_at TEXT NOT NULL DEFAULT (datetime('now'))
-	);
+func openPostgres(dsn string) (Store, error) {
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("connect: %w", err)
+	}
 
-	CREATE INDEX IF NOT EXISTS idx_sessions_device_open
-		ON sessions(device_id)
-		WHERE ended_at IS NULL;
-	
-	CREATE INDEX IF NOT EXISTS idx_sessions_book_started
-		ON sessions(book_id, started_at DESC);
-	
-	CREATE INDEX IF NOT EXISTS idx_sessions_date
-		ON sessions(started_at);
-	
-	CREATE INDEX IF NOT EXISTS idx_sessions_device_started
-		ON sessions(device_id, started_at DESC);`
+	if err := MigratePostgres(db); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("migrate: %w", err)
+	}
+	return NewPostgresStore(db), nil
 }
@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var deviceTypes = map[string]bool{
+	"iphone":  true,
+	"ipad":    true,
+	"mac":     true,
+	"ereader": true,
+	"other":   true,
+}
+
+type registerDeviceRequest struct {
+	DeviceID string `json:"device_id"`
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Timezone string `json:"timezone,omitempty"`
+}
+
+type deviceItem struct {
+	ID            int64   `json:"id"`
+	DeviceID      string  `json:"device_id"`
+	Name          string  `json:"name"`
+	Type          string  `json:"type"`
+	Timezone      string  `json:"timezone"`
+	CreatedAt     string  `json:"created_at"`
+	LastSeenAt    *string `json:"last_seen_at,omitempty"`
+	OpenSessionID *int64  `json:"open_session_id,omitempty"`
+	HasOpen       bool    `json:"has_open_session"`
+}
+
+// boundDevice is the device a device token is bound to, if any.
+func boundDevice(ctx context.Context) string {
+	d, _ := ctx.Value(deviceCtxKey).(string)
+	return d
+}
+
+// deviceLocation is the time zone a user's device is registered with, or UTC
+// for devices that are not registered.
+func deviceLocation(ctx context.Context, q Querier, userID int64, deviceID string) (*time.Location, error) {
+	var tz string
+	err := q.QueryRow(ctx, `SELECT timezone FROM devices WHERE user_id = ? AND device_id = ?`, userID, deviceID).Scan(&tz)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.UTC, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return time.UTC, nil
+	}
+	return loc, nil
+}
+
+// resolveDevice picks the device a session request is for: the device_id in
+// the request, or the device the token is bound to. With StrictDevices the
+// device must be registered. On failure it writes the error and returns false.
+func (a *App) resolveDevice(w http.ResponseWriter, r *http.Request, requested string) (string, bool) {
+	requested = strings.TrimSpace(requested)
+	bound := boundDevice(r.Context())
+	defer func() { logDevice(r.Context(), requested) }()
+
+	switch {
+	case requested == "" && bound != "":
+		requested = bound
+	case requested == "":
+		writeError(w, r, ErrRequired.Field("device_id", "device_id is required"))
+		return "", false
+	case bound != "" && requested != bound:
+		writeError(w, r, ErrDeviceMismatch)
+		return "", false
+	}
+
+	if a.StrictDevices {
+		var n int
+		err := a.Store.QueryRow(r.Context(), `SELECT COUNT(*) FROM devices WHERE user_id = ? AND device_id = ?`,
+			userID(r.Context()), requested).Scan(&n)
+		if err != nil {
+			serverError(w, r, err, "internal error")
+			return "", false
+		}
+		if n == 0 {
+			writeError(w, r, ErrUnknownDevice.Field("device_id", ErrUnknownDevice.Message))
+			return "", false
+		}
+	}
+	return requested, true
+}
+
+func (a *App) registerDevice(w http.ResponseWriter, r *http.Request) {
+	var req registerDeviceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, ErrInvalidJSON)
+		return
+	}
+	req.DeviceID = strings.TrimSpace(req.DeviceID)
+	req.Name = strings.TrimSpace(req.Name)
+	req.Type = strings.ToLower(strings.TrimSpace(req.Type))
+	req.Timezone = strings.TrimSpace(req.Timezone)
+
+	if req.DeviceID == "" {
+		writeError(w, r, ErrRequired.Field("device_id", "device_id is required"))
+		return
+	}
+	if req.Name == "" {
+		writeError(w, r, ErrRequired.Field("name", "name is required"))
+		return
+	}
+	if req.Type == "e-reader" {
+		req.Type = "ereader"
+	}
+	if !deviceTypes[req.Type] {
+		writeError(w, r, ErrInvalidValue.Field("type", "type must be one of iphone, ipad, mac, ereader, other"))
+		return
+	}
+	if req.Timezone == "" {
+		req.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(req.Timezone); err != nil {
+		writeError(w, r, ErrInvalidTimeZone.Field("timezone", "timezone must be an IANA time zone (e.g., Europe/Berlin)"))
+		return
+	}
+
+	uid := userID(r.Context())
+	out := deviceItem{
+		DeviceID:  req.DeviceID,
+		Name:      req.Name,
+		Type:      req.Type,
+		Timezone:  req.Timezone,
+		CreatedAt: timeOrNowRFC3339(nil),
+	}
+	err := a.Store.WithTx(r.Context(), func(ctx context.Context, tx Querier) error {
+		var exists int
+		if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM devices WHERE user_id = ? AND device_id = ?`, uid, req.DeviceID).Scan(&exists); err != nil {
+			return err
+		}
+		if exists > 0 {
+			return ErrDeviceExists
+		}
+		return tx.QueryRow(ctx, `
+			INSERT INTO devices (user_id, device_id, name, type, timezone, created_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+			RETURNING id
+		`, uid, out.DeviceID, out.Name, out.Type, out.Timezone, out.CreatedAt).Scan(&out.ID)
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, out)
+}
+
+func (a *App) listDevices(w http.ResponseWriter, r *http.Request) {
+	const q = `
+SELECT
+  d.id,
+  d.device_id,
+  d.name,
+  d.type,
+  d.timezone,
+  d.created_at,
+  (SELECT MAX(COALESCE(s.ended_at, s.started_at))
+   FROM sessions s
+   WHERE s.user_id = d.user_id AND s.device_id = d.device_id) AS last_seen_at,
+  (SELECT s.id
+   FROM sessions s
+   WHERE s.user_id = d.user_id AND s.device_id = d.device_id AND s.ended_at IS NULL
+   LIMIT 1) AS open_session_id
+FROM devices d
+WHERE d.user_id = ?
+ORDER BY d.name, d.id;`
+
+	rows, err := a.Store.Query(r.Context(), q, userID(r.Context()))
+	if err != nil {
+		serverError(w, r, err, "query failed")
+		return
+	}
+	defer rows.Close()
+
+	items := make([]deviceItem, 0)
+	for rows.Next() {
+		var it deviceItem
+		if err := rows.Scan(&it.ID, &it.DeviceID, &it.Name, &it.Type, &it.Timezone, &it.CreatedAt, &it.LastSeenAt, &it.OpenSessionID); err != nil {
+			serverError(w, r, err, "scan failed")
+			return
+		}
+		it.HasOpen = it.OpenSessionID != nil
+		items = append(items, it)
+	}
+	if err := rows.Err(); err != nil {
+		serverError(w, r, err, "row error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"items": items,
+		"meta":  map[string]any{"count": len(items)},
+	})
+}
@@ -0,0 +1,142 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/mk-slmn/booksmart/services/api/handlers"
+)
+
+func registerDevice(t *testing.T, r http.Handler, deviceID, typ string) {
+	t.Helper()
+
+	w := doJSON(t, r, http.MethodPost, "/v1/devices", map[string]any{
+		"device_id": deviceID,
+		"name":      "My " + typ,
+		"type":      typ,
+		"timezone":  "Europe/Berlin",
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("register expected 201, got %d body=%s", w.Code, w.Body.String())
+	}
+}
+
+func TestDevices_ListShowsLastSeenAndOpenSession(t *testing.T) {
+	r := newTestServer(t)
+
+	registerDevice(t, r, "iphone-14", "iPhone")
+	registerDevice(t, r, "kobo", "e-reader")
+
+	w := doJSON(t, r, http.MethodPost, "/v1/devices", map[string]any{"device_id": "kobo", "name": "Again", "type": "ereader"})
+	if w.Code != http.StatusConflict {
+		t.Fatalf("duplicate expected 409, got %d", w.Code)
+	}
+
+	w = doJSON(t, r, http.MethodPost, "/v1/session/start", map[string]any{
+		"device_id":  "iphone-14",
+		"book_title": "Dune",
+		"started_at": "2025-09-16T20:00:00Z",
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("start expected 201, got %d body=%s", w.Code, w.Body.String())
+	}
+
+	w = doJSON(t, r, http.MethodGet, "/v1/devices", nil)
+	var resp struct {
+		Items []struct {
+			DeviceID   string  `json:"device_id"`
+			Type       string  `json:"type"`
+			LastSeenAt *string `json:"last_seen_at"`
+			HasOpen    bool    `json:"has_open_session"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if len(resp.Items) != 2 {
+		t.Fatalf("expected 2 devices, got %s", w.Body.String())
+	}
+	for _, d := range resp.Items {
+		switch d.DeviceID {
+		case "iphone-14":
+			if !d.HasOpen || d.LastSeenAt == nil || *d.LastSeenAt != "2025-09-16T20:00:00Z" {
+				t.Fatalf("unexpected iphone entry: %+v", d)
+			}
+		case "kobo":
+			if d.HasOpen || d.LastSeenAt != nil || d.Type != "ereader" {
+				t.Fatalf("unexpected kobo entry: %+v", d)
+			}
+		}
+	}
+}
+
+func TestDevices_StrictModeRejectsUnknown(t *testing.T) {
+	r := (&handlers.App{Store: newTestDB(t), Logger: quietLogger, StrictDevices: true}).Routes()
+
+	registerDevice(t, r, "ipad", "ipad")
+
+	w := doJSON(t, r, http.MethodPost, "/v1/session/start", map[string]any{"device_id": "ipda", "book_title": "Dune"})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("typo device expected 400, got %d body=%s", w.Code, w.Body.String())
+	}
+	w = doJSON(t, r, http.MethodPost, "/v1/session/start", map[string]any{"device_id": "ipad", "book_title": "Dune"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("registered device expected 201, got %d body=%s", w.Code, w.Body.String())
+	}
+}
+
+func TestDevices_TokenStandsInForDeviceID(t *testing.T) {
+	r := newTestServer(t)
+
+	registerDevice(t, r, "iphone-14", "iphone")
+	w := doJSON(t, r, http.MethodPost, "/v1/tokens", map[string]any{"name": "iphone", "device_id": "iphone-14"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create token expected 201, got %d body=%s", w.Code, w.Body.String())
+	}
+	var tok map[string]any
+	_ = json.Unmarshal(w.Body.Bytes(), &tok)
+	token := tok["token"].(string)
+
+	w = withToken(t, r, token, http.MethodPost, "/v1/session/start", map[string]any{"book_title": "Dune"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("start expected 201, got %d body=%s", w.Code, w.Body.String())
+	}
+	var started map[string]any
+	_ = json.Unmarshal(w.Body.Bytes(), &started)
+	if started["device_id"] != "iphone-14" {
+		t.Fatalf("expected bound device, got %#v", started["device_id"])
+	}
+
+	w = withToken(t, r, token, http.MethodPost, "/v1/session/stop", map[string]any{"device_id": "ipad"})
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("other device expected 403, got %d body=%s", w.Code, w.Body.String())
+	}
+}
+
+func TestDevices_TokenOnlyMintsTokensForItsDevice(t *testing.T) {
+	r := newTestServer(t)
+
+	registerDevice(t, r, "iphone-14", "iphone")
+	registerDevice(t, r, "ipad", "ipad")
+	w := doJSON(t, r, http.MethodPost, "/v1/tokens", map[string]any{"name": "iphone", "device_id": "iphone-14"})
+	var tok map[string]any
+	_ = json.Unmarshal(w.Body.Bytes(), &tok)
+	token := tok["token"].(string)
+
+	w = withToken(t, r, token, http.MethodPost, "/v1/tokens", map[string]any{"name": "anywhere"})
+	var minted map[string]any
+	_ = json.Unmarshal(w.Body.Bytes(), &minted)
+	if w.Code != http.StatusCreated || minted["device_id"] != "iphone-14" {
+		t.Fatalf("token minted by a device token should be bound to it, got %d body=%s", w.Code, w.Body.String())
+	}
+	w = withToken(t, r, minted["token"].(string), http.MethodPost, "/v1/session/start", map[string]any{"device_id": "ipad", "book_title": "Dune"})
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("minted token on another device expected 403, got %d body=%s", w.Code, w.Body.String())
+	}
+
+	w = withToken(t, r, token, http.MethodPost, "/v1/tokens", map[string]any{"name": "ipad", "device_id": "ipad"})
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("token for another device expected 403, got %d body=%s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+)
+
+// Error is an API error. Code is a stable, machine-readable identifier that
+// clients (and Shortcuts) can branch on; Message is for people and may be
+// reworded. Details names the request fields that failed validation.
+//
+// The Err… values below are the errors the API can return. Handlers return
+// or wrap them, refine them with WithMessage or Field, and writeError maps
+// them to a response; anything else is answered as an internal error.
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+	Details []FieldError
+}
+
+// FieldError describes one invalid request field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+var (
+	ErrInvalidJSON     = &Error{Status: http.StatusBadRequest, Code: "invalid_json", Message: "invalid JSON body"}
+	ErrRequired        = &Error{Status: http.StatusBadRequest, Code: "required", Message: "a required field is missing"}
+	ErrInvalidValue    = &Error{Status: http.StatusBadRequest, Code: "invalid_value", Message: "a field has an invalid value"}
+	ErrInvalidID       = &Error{Status: http.StatusBadRequest, Code: "invalid_id", Message: "id must be a positive integer"}
+	ErrInvalidPage     = &Error{Status: http.StatusBadRequest, Code: "invalid_page", Message: "page numbers must be >= 0"}
+	ErrInvalidEndPage  = &Error{Status: http.StatusBadRequest, Code: "invalid_end_page", Message: "end_page must be >= 0"}
+	ErrInvalidTime     = &Error{Status: http.StatusBadRequest, Code: "invalid_time", Message: "times must be RFC3339 (e.g., 2025-09-16T21:25:00Z)"}
+	ErrInvalidTimeZone = &Error{Status: http.StatusBadRequest, Code: "invalid_timezone", Message: "time zones must be IANA names (e.g., Europe/Berlin)"}
+	ErrInvalidRange    = &Error{Status: http.StatusBadRequest, Code: "invalid_range", Message: "from must be before to"}
+	ErrUnknownDevice   = &Error{Status: http.StatusBadRequest, Code: "unknown_device", Message: "unknown device_id; register it with POST /v1/devices"}
+	ErrInvalidSnapshot = &Error{Status: http.StatusBadRequest, Code: "invalid_snapshot", Message: "not a Booksmart SQLite snapshot"}
+	ErrInvalidArchive  = &Error{Status: http.StatusBadRequest, Code: "invalid_archive", Message: "not a valid Booksmart archive"}
+	ErrInvalidCSV      = &Error{Status: http.StatusBadRequest, Code: "invalid_csv", Message: "the uploaded file is not valid CSV"}
+	ErrInvalidClips    = &Error{Status: http.StatusBadRequest, Code: "invalid_clippings", Message: "the uploaded file is not a Kindle My Clippings.txt"}
+
+	ErrAuthRequired = &Error{Status: http.StatusUnauthorized, Code: "authentication_required", Message: "authentication required"}
+	ErrInvalidToken = &Error{Status: http.StatusUnauthorized, Code: "invalid_token", Message: "invalid or revoked token"}
+	ErrUnknownUser  = &Error{Status: http.StatusUnauthorized, Code: "unknown_user", Message: "unknown user"}
+
+	ErrReadOnlyToken   = &Error{Status: http.StatusForbidden, Code: "read_only_token", Message: "token is read-only"}
+	ErrDeviceMismatch  = &Error{Status: http.StatusForbidden, Code: "device_mismatch", Message: "token is bound to another device"}
+	ErrDefaultUserOnly = &Error{Status: http.StatusForbidden, Code: "default_user_only", Message: "only the default user can do this"}
+
+	ErrNotFound        = &Error{Status: http.StatusNotFound, Code: "not_found", Message: "not found"}
+	ErrNoOpenSession   = &Error{Status: http.StatusNotFound, Code: "no_open_session", Message: "no open session for this device"}
+	ErrNoPriorSession  = &Error{Status: http.StatusNotFound, Code: "no_prior_session", Message: "no prior session to continue"}
+	ErrBookNotFound    = &Error{Status: http.StatusNotFound, Code: "book_not_found", Message: "book not found"}
+	ErrGoalNotFound    = &Error{Status: http.StatusNotFound, Code: "goal_not_found", Message: "goal not found"}
+	ErrNoteNotFound    = &Error{Status: http.StatusNotFound, Code: "note_not_found", Message: "note not found"}
+	ErrSessionNotFound = &Error{Status: http.StatusNotFound, Code: "session_not_found", Message: "session not found"}
+	ErrTokenNotFound   = &Error{Status: http.StatusNotFound, Code: "token_not_found", Message: "token not found"}
+	ErrUserNotFound    = &Error{Status: http.StatusNotFound, Code: "user_not_found", Message: "user not found"}
+
+	ErrMethodNotAllowed = &Error{Status: http.StatusMethodNotAllowed, Code: "method_not_allowed", Message: "method not allowed"}
+
+	ErrDeviceExists = &Error{Status: http.StatusConflict, Code: "device_exists", Message: "device is already registered"}
+	ErrGoalExists   = &Error{Status: http.StatusConflict, Code: "goal_exists", Message: "a goal of this kind already exists"}
+	ErrUserExists   = &Error{Status: http.StatusConflict, Code: "user_exists", Message: "a user with this name already exists"}
+
+	ErrSnapshotTooNew = &Error{Status: http.StatusConflict, Code: "snapshot_too_new", Message: "snapshot has a newer schema than this server; upgrade the server first"}
+
+	ErrTooLarge = &Error{Status: http.StatusRequestEntityTooLarge, Code: "too_large", Message: "request body is too large"}
+
+	ErrNotSupported = &Error{Status: http.StatusNotImplemented, Code: "not_supported", Message: "not supported by this database"}
+)
+
+// codeInternal is the code of every 500 response.
+const codeInternal = "internal_error"
+
+func (e *Error) Error() string { return e.Message }
+
+// Is matches by code, so refined copies still match their Err… value.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// WithMessage returns a copy of e with a more specific message.
+func (e *Error) WithMessage(msg string) *Error {
+	c := *e
+	c.Message = msg
+	return &c
+}
+
+// Field returns a copy of e blaming the request field, with msg as the
+// message of both the field and the error.
+func (e *Error) Field(field, msg string) *Error {
+	c := *e
+	c.Message = msg
+	c.Details = append(slices.Clip(e.Details), FieldError{Field: field, Code: e.Code, Message: msg})
+	return &c
+}
+
+// writeError answers with the status and code of the *Error in err's chain.
+// Any other error is unexpected and becomes a 500.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var e *Error
+	if !errors.As(err, &e) {
+		serverError(w, r, err, "internal error")
+		return
+	}
+	body := map[string]any{
+		"code":    e.Code,
+		"message": e.Message,
+	}
+	if len(e.Details) > 0 {
+		body["details"] = e.Details
+	}
+	writeJSON(w, e.Status, map[string]any{"error": body})
+}
@@ -0,0 +1,93 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type errorBody struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+		Details []struct {
+			Field string `json:"field"`
+			Code  string `json:"code"`
+		} `json:"details"`
+	} `json:"error"`
+}
+
+func decodeError(t *testing.T, w *httptest.ResponseRecorder) errorBody {
+	t.Helper()
+	var e errorBody
+	if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil {
+		t.Fatalf("decode error body %q: %v", w.Body.String(), err)
+	}
+	return e
+}
+
+func TestErrors_StableCodes(t *testing.T) {
+	r := newTestServer(t)
+
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		body   any
+		status int
+		code   string
+		field  string
+	}{
+		{"no open session", http.MethodPost, "/v1/session/stop", map[string]any{"device_id": "ipad"}, http.StatusNotFound, "no_open_session", ""},
+		{"nothing to continue", http.MethodPost, "/v1/session/continue", map[string]any{"device_id": "ipad"}, http.StatusNotFound, "no_prior_session", ""},
+		{"missing title", http.MethodPost, "/v1/session/start", map[string]any{"device_id": "ipad"}, http.StatusBadRequest, "required", "book_title"},
+		{"bad start time", http.MethodPost, "/v1/session/start", map[string]any{"device_id": "ipad", "book_title": "Dune", "started_at": "yesterday"}, http.StatusBadRequest, "invalid_time", "started_at"},
+		{"bad goal kind", http.MethodPost, "/v1/goals", map[string]any{"kind": "words_per_day", "target": 1}, http.StatusBadRequest, "invalid_value", "kind"},
+		{"missing goal", http.MethodGet, "/v1/goals/99", nil, http.StatusNotFound, "goal_not_found", ""},
+		{"bad id", http.MethodGet, "/v1/books/abc", nil, http.StatusBadRequest, "invalid_id", "id"},
+		{"unknown route", http.MethodGet, "/v1/nope", nil, http.StatusNotFound, "not_found", ""},
+	}
+	for _, tc := range cases {
+		w := doJSON(t, r, tc.method, tc.path, tc.body)
+		if w.Code != tc.status {
+			t.Errorf("%s: expected %d, got %d body=%s", tc.name, tc.status, w.Code, w.Body.String())
+			continue
+		}
+		e := decodeError(t, w)
+		if e.Error.Code != tc.code || e.Error.Message == "" {
+			t.Errorf("%s: expected code %q, got %+v", tc.name, tc.code, e.Error)
+		}
+		if tc.field != "" && (len(e.Error.Details) != 1 || e.Error.Details[0].Field != tc.field || e.Error.Details[0].Code != tc.code) {
+			t.Errorf("%s: expected details for %s, got %+v", tc.name, tc.field, e.Error.Details)
+		}
+	}
+}
+
+func TestErrors_InvalidEndPageKeepsSessionOpen(t *testing.T) {
+	r := newTestServer(t)
+	doJSON(t, r, http.MethodPost, "/v1/session/start", map[string]any{"device_id": "ipad", "book_title": "Dune"})
+
+	w := doJSON(t, r, http.MethodPost, "/v1/session/stop", map[string]any{"device_id": "ipad", "end_page": -1})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d body=%s", w.Code, w.Body.String())
+	}
+	if e := decodeError(t, w); e.Error.Code != "invalid_end_page" || len(e.Error.Details) != 1 || e.Error.Details[0].Field != "end_page" {
+		t.Fatalf("unexpected error: %+v", e.Error)
+	}
+
+	if w := doJSON(t, r, http.MethodGet, "/v1/sessions/open?device_id=ipad", nil); w.Code != http.StatusOK {
+		t.Fatalf("session should still be open, got %d", w.Code)
+	}
+}
+
+func TestErrors_InvalidJSON(t *testing.T) {
+	r := newTestServer(t)
+	req := httptest.NewRequest(http.MethodPost, "/v1/session/start", strings.NewReader("{"))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest || decodeError(t, w).Error.Code != "invalid_json" {
+		t.Fatalf("expected invalid_json, got %d body=%s", w.Code, w.Body.String())
+	}
+}
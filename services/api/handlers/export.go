@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Exports are flushed every exportFlushRows rows, and each flush pushes the
+// write deadline exportRowWindow out, so an export may outlast the server's
+// WriteTimeout as long as it keeps making progress.
+const (
+	exportFlushRows = 500
+	exportRowWindow = 30 * time.Second
+)
+
+// sessionExportColumns is the column order of sessions.csv. New columns are
+// only ever appended.
+var sessionExportColumns = []string{
+	"id", "book_id", "book_title", "author", "source", "device_id",
+	"start_page", "end_page", "pages", "started_at", "ended_at",
+	"duration_seconds", "status", "created_at",
+}
+
+var bookExportColumns = []string{
+	"id", "title", "author", "source", "total_pages", "created_at",
+}
+
+// exportStream writes rows as they are read, flushing every exportFlushRows.
+// Once the first byte is out the status is fixed, so errors after that only
+// end the stream and are logged.
+type exportStream struct {
+	r  *http.Request
+	rc *http.ResponseController
+	cw *csv.Writer // nil for NDJSON
+	n  int
+}
+
+func newExportStream(w http.ResponseWriter, r *http.Request, contentType, filename string) *exportStream {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Now().Add(exportRowWindow))
+	return &exportStream{r: r, rc: rc}
+}
+
+// csv returns a CSV writer for the stream, with header as its first row.
+func (s *exportStream) csv(w http.ResponseWriter, header []string) *csv.Writer {
+	s.cw = csv.NewWriter(w)
+	_ = s.cw.Write(header)
+	return s.cw
+}
+
+// row is called after each row is written.
+func (s *exportStream) row() {
+	if s.n++; s.n%exportFlushRows == 0 {
+		s.flush()
+	}
+}
+
+func (s *exportStream) flush() {
+	if s.cw != nil {
+		s.cw.Flush()
+	}
+	_ = s.rc.Flush()
+	_ = s.rc.SetWriteDeadline(time.Now().Add(exportRowWindow))
+}
+
+// done flushes what is left and logs err, the error that ended the rows.
+func (s *exportStream) done(err error) {
+	s.flush()
+	if err != nil {
+		logError(s.r.Context(), err)
+	}
+}
+
+// sessionExportQuery selects the sessions matching the request's
+// listSessions filters and sort.
+func (a *App) sessionExportQuery(w http.ResponseWriter, r *http.Request) (string, []any, bool) {
+	f, err := parseSessionFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, r, err)
+		return "", nil, false
+	}
+	order, err := parseSessionSort(r.URL.Query())
+	if err != nil {
+		writeError(w, r, err)
+		return "", nil, false
+	}
+	conds, args := f.where(userID(r.Context()))
+	query := sessionSelect + `
+FROM sessions s
+JOIN books b ON b.id = s.book_id
+WHERE ` + strings.Join(conds, " AND ") + `
+` + order.orderBy()
+	return query, args, true
+}
+
+func (a *App) exportSessionsCSV(w http.ResponseWriter, r *http.Request) {
+	query, args, ok := a.sessionExportQuery(w, r)
+	if !ok {
+		return
+	}
+	rows, err := a.Store.Query(r.Context(), query, args...)
+	if err != nil {
+		serverError(w, r, err, "query failed")
+		return
+	}
+	defer rows.Close()
+
+	s := newExportStream(w, r, "text/csv; charset=utf-8", "sessions.csv")
+	cw := s.csv(w, sessionExportColumns)
+	for rows.Next() {
+		var it sessionListItem
+		if err := rows.Scan(it.fields()...); err != nil {
+			s.done(err)
+			return
+		}
+		var pages *int
+		if it.EndPage != nil {
+			p := *it.EndPage - it.StartPage
+			pages = &p
+		}
+		_ = cw.Write([]string{
+			strconv.FormatInt(it.ID, 10),
+			strconv.FormatInt(it.BookID, 10),
+			it.BookTitle,
+			csvString(it.Author),
+			csvString(it.Source),
+			it.DeviceID,
+			strconv.Itoa(it.StartPage),
+			csvInt(it.EndPage),
+			csvInt(pages),
+			it.StartedAt,
+			csvString(it.EndedAt),
+			csvInt64(it.DurationSeconds),
+			it.Status,
+			it.CreatedAt,
+		})
+		s.row()
+	}
+	s.done(rows.Err())
+}
+
+func (a *App) exportSessionsNDJSON(w http.ResponseWriter, r *http.Request) {
+	query, args, ok := a.sessionExportQuery(w, r)
+	if !ok {
+		return
+	}
+	rows, err := a.Store.Query(r.Context(), query, args...)
+	if err != nil {
+		serverError(w, r, err, "query failed")
+		return
+	}
+	defer rows.Close()
+
+	s := newExportStream(w, r, "application/x-ndjson", "sessions.ndjson")
+	enc := json.NewEncoder(w)
+	for rows.Next() {
+		var it sessionListItem
+		if err := rows.Scan(it.fields()...); err != nil {
+			s.done(err)
+			return
+		}
+		if err := enc.Encode(it); err != nil {
+			s.done(err)
+			return
+		}
+		s.row()
+	}
+	s.done(rows.Err())
+}
+
+// exportBooksCSV exports the user's books oldest first; q filters by title
+// or author like listBooks.
+func (a *App) exportBooksCSV(w http.ResponseWriter, r *http.Request) {
+	where := "WHERE b.user_id = ?"
+	args := []any{userID(r.Context())}
+	if q := strings.TrimSpace(r.URL.Query().Get("q")); q != "" {
+		where += " AND (LOWER(b.title) LIKE ? OR LOWER(COALESCE(b.author,'')) LIKE ?)"
+		like := "%" + strings.ToLower(q) + "%"
+		args = append(args, like, like)
+	}
+	rows, err := a.Store.Query(r.Context(), `
+SELECT b.id, b.title, b.author, b.source, b.total_pages, b.created_at
+FROM books b
+`+where+`
+ORDER BY b.created_at ASC, b.id ASC`, args...)
+	if err != nil {
+		serverError(w, r, err, "query failed")
+		return
+	}
+	defer rows.Close()
+
+	s := newExportStream(w, r, "text/csv; charset=utf-8", "books.csv")
+	cw := s.csv(w, bookExportColumns)
+	for rows.Next() {
+		var (
+			id          int64
+			title       string
+			author, src *string
+			totalPages  *int
+			createdAt   string
+		)
+		if err := rows.Scan(&id, &title, &author, &src, &totalPages, &createdAt); err != nil {
+			s.done(err)
+			return
+		}
+		_ = cw.Write([]string{
+			strconv.FormatInt(id, 10), title, csvString(author), csvString(src), csvInt(totalPages), createdAt,
+		})
+		s.row()
+	}
+	s.done(rows.Err())
+}
+
+func csvString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func csvInt(n *int) string {
+	if n == nil {
+		return ""
+	}
+	return strconv.Itoa(*n)
+}
+
+func csvInt64(n *int64) string {
+	if n == nil {
+		return ""
+	}
+	return strconv.FormatInt(*n, 10)
+}
@@ -0,0 +1,88 @@
+package handlers_test
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestExport_SessionsCSV(t *testing.T) {
+	r := newTestServer(t)
+	readSession(t, r, "ipad", "Dune", 0, 10, "2025-09-01T10:00:00Z", "2025-09-01T10:30:00Z")
+	readSession(t, r, "kindle", "Emma, or Pride", 0, 5, "2025-09-02T10:00:00Z", "2025-09-02T10:10:00Z")
+	doJSON(t, r, http.MethodPost, "/v1/session/start", map[string]any{"device_id": "ipad", "book_title": "Dune", "start_page": 10, "started_at": "2025-09-03T10:00:00Z"})
+
+	w := doJSON(t, r, http.MethodGet, "/v1/export/sessions.csv?sort=started_at&order=asc", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	recs, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("parse csv: %v", err)
+	}
+	want := []string{"id", "book_id", "book_title", "author", "source", "device_id", "start_page", "end_page", "pages",
+		"started_at", "ended_at", "duration_seconds", "status", "created_at"}
+	if strings.Join(recs[0], ",") != strings.Join(want, ",") {
+		t.Fatalf("unexpected header %v", recs[0])
+	}
+	if len(recs) != 4 {
+		t.Fatalf("expected 3 rows, got %d: %v", len(recs)-1, recs)
+	}
+	first := recs[1]
+	if first[2] != "Dune" || first[7] != "10" || first[8] != "10" || first[9] != "2025-09-01T10:00:00Z" ||
+		first[10] != "2025-09-01T10:30:00Z" || first[11] != "1800" || first[12] != "closed" {
+		t.Fatalf("unexpected first row %v", first)
+	}
+	if recs[2][2] != "Emma, or Pride" {
+		t.Fatalf("title with a comma should survive quoting, got %q", recs[2][2])
+	}
+	if open := recs[3]; open[7] != "" || open[10] != "" || open[11] != "" || open[12] != "open" {
+		t.Fatalf("open session should have empty end columns: %v", open)
+	}
+
+	w = doJSON(t, r, http.MethodGet, "/v1/export/sessions.csv?device_id=kindle", nil)
+	if recs, _ := csv.NewReader(w.Body).ReadAll(); len(recs) != 2 || recs[1][5] != "kindle" {
+		t.Fatalf("device filter not applied: %v", recs)
+	}
+	if w := doJSON(t, r, http.MethodGet, "/v1/export/sessions.csv?status=paused", nil); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a bad filter, got %d", w.Code)
+	}
+}
+
+func TestExport_SessionsNDJSONAndBooksCSV(t *testing.T) {
+	r := newTestServer(t)
+	readSession(t, r, "ipad", "Dune", 0, 10, "2025-09-01T10:00:00Z", "2025-09-01T10:30:00Z")
+	readSession(t, r, "ipad", "Emma", 0, 5, "2025-09-02T10:00:00Z", "2025-09-02T10:10:00Z")
+
+	w := doJSON(t, r, http.MethodGet, "/v1/export/sessions.ndjson?status=closed", nil)
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	var titles []string
+	sc := bufio.NewScanner(w.Body)
+	for sc.Scan() {
+		var it map[string]any
+		if err := json.Unmarshal(sc.Bytes(), &it); err != nil {
+			t.Fatalf("line %q: %v", sc.Text(), err)
+		}
+		titles = append(titles, it["book_title"].(string))
+	}
+	if len(titles) != 2 || titles[0] != "Emma" || titles[1] != "Dune" {
+		t.Fatalf("expected newest activity first, got %v", titles)
+	}
+
+	w = doJSON(t, r, http.MethodGet, "/v1/export/books.csv", nil)
+	recs, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("parse csv: %v", err)
+	}
+	if len(recs) != 3 || strings.Join(recs[0], ",") != "id,title,author,source,total_pages,created_at" || recs[1][1] != "Dune" {
+		t.Fatalf("unexpected books export %v", recs)
+	}
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// forecastWindowDays is how far back recent reading habits are measured.
+const forecastWindowDays = 28
+
+// forecastZ widens the finish-date range to roughly an 80% interval.
+const forecastZ = 1.28
+
+type forecast struct {
+	PagesRemaining     int      `json:"pages_remaining"`
+	PagesPerHour       float64  `json:"pages_per_hour"`
+	SpeedBasis         string   `json:"speed_basis"` // "recent" or "book"/"overall"
+	DailyMinutes       float64  `json:"daily_minutes"`
+	DailyMinutesStddev float64  `json:"daily_minutes_stddev"`
+	WindowDays         int      `json:"window_days"`
+	EstimatedDays      *float64 `json:"estimated_days,omitempty"`
+	EstimatedFinish    *string  `json:"estimated_finish,omitempty"`
+	EarliestFinish     *string  `json:"earliest_finish,omitempty"`
+	LatestFinish       *string  `json:"latest_finish,omitempty"`
+}
+
+// readingPace is what the forecast knows about recent reading habits.
+type readingPace struct {
+	Now          time.Time
+	WindowDays   int
+	DailyMinutes []float64 // one entry per UTC day in the window, zeros included
+	Recent       *float64  // pages per hour over the window, nil if no usable sessions
+}
+
+// loadReadingPace collects a user's per-day minutes and pages per hour for
+// the last windowDays UTC days, today included.
+func loadReadingPace(ctx context.Context, q Querier, userID int64, now time.Time, windowDays int) (_ readingPace, err error) {
+	ctx, span := startSpan(ctx, "loadReadingPace")
+	defer func() { endSpan(span, err) }()
+
+	p := readingPace{
+		Now:          now,
+		WindowDays:   windowDays,
+		DailyMinutes: make([]float64, windowDays),
+	}
+	today := now.UTC().Truncate(24 * time.Hour)
+	since := today.AddDate(0, 0, 1-windowDays)
+	sinceStr := since.Format(time.RFC3339)
+
+	rows, err := q.Query(ctx, `
+SELECT ended_at, duration_seconds
+FROM sessions
+WHERE user_id = ?
+  AND ended_at IS NOT NULL
+  AND duration_seconds > 0
+  AND ended_at >= ?`, userID, sinceStr)
+	if err != nil {
+		return p, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var endedAt string
+		var sec int64
+		if err := rows.Scan(&endedAt, &sec); err != nil {
+			return p, err
+		}
+		t, err := parseRFC3339UTC(endedAt)
+		if err != nil {
+			continue
+		}
+		i := int(t.Sub(since) / (24 * time.Hour))
+		if i >= 0 && i < windowDays {
+			p.DailyMinutes[i] += float64(sec) / 60
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return p, err
+	}
+
+	samples, err := loadSpeedSamples(ctx, q, userID, "AND s.ended_at >= ?", sinceStr)
+	if err != nil {
+		return p, err
+	}
+	var st speedStat
+	for _, x := range samples {
+		st.add(x)
+	}
+	if st.PagesPerHour != nil && *st.PagesPerHour > 0 {
+		p.Recent = st.PagesPerHour
+	}
+	return p, nil
+}
+
+// forecastFinish predicts when remaining pages will be read. Speed comes from
+// the recent window, falling back to fallback (usually the book's own speed).
+// The range treats each day's minutes as independent draws: over d days the
+// total is about mean·d ± z·stddev·√d, so it widens with the horizon.
+func forecastFinish(p readingPace, remaining int, fallback *speed) *forecast {
+	f := &forecast{
+		PagesRemaining: remaining,
+		WindowDays:     p.WindowDays,
+	}
+	switch {
+	case p.Recent != nil:
+		f.PagesPerHour = *p.Recent
+		f.SpeedBasis = "recent"
+	case fallback != nil:
+		f.PagesPerHour = fallback.PagesPerHour
+		f.SpeedBasis = fallback.Basis
+	default:
+		return nil
+	}
+
+	mean, stddev := meanStddev(p.DailyMinutes)
+	f.DailyMinutes = round2(mean)
+	f.DailyMinutesStddev = round2(stddev)
+	if mean <= 0 {
+		return f
+	}
+
+	minutesNeeded := float64(remaining) / f.PagesPerHour * 60
+	today := p.Now.UTC().Truncate(24 * time.Hour)
+	finish := func(days float64) *string {
+		s := today.AddDate(0, 0, int(math.Ceil(days))).Format(time.DateOnly)
+		return &s
+	}
+
+	days := round2(minutesNeeded / mean)
+	f.EstimatedDays = &days
+	f.EstimatedFinish = finish(minutesNeeded / mean)
+	f.EarliestFinish = finish(daysToRead(minutesNeeded, mean, forecastZ*stddev))
+	f.LatestFinish = finish(daysToRead(minutesNeeded, mean, -forecastZ*stddev))
+	return f
+}
+
+// daysToRead solves mean·d + spread·√d = minutes for d: the days until
+// minutes are read at mean minutes a day, spread·√d ahead of (or, when
+// spread is negative, behind) that pace.
+func daysToRead(minutes, mean, spread float64) float64 {
+	x := (-spread + math.Sqrt(spread*spread+4*mean*minutes)) / (2 * mean)
+	return x * x
+}
+
+func meanStddev(xs []float64) (mean, stddev float64) {
+	if len(xs) == 0 {
+		return 0, 0
+	}
+	for _, x := range xs {
+		mean += x
+	}
+	mean /= float64(len(xs))
+	if len(xs) < 2 {
+		return mean, 0
+	}
+	var ss float64
+	for _, x := range xs {
+		ss += (x - mean) * (x - mean)
+	}
+	return mean, math.Sqrt(ss / float64(len(xs)-1))
+}
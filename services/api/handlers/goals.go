@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+const (
+	goalBooksPerYear  = "books_per_year"
+	goalMinutesPerDay = "minutes_per_day"
+	goalPagesPerWeek  = "pages_per_week"
+)
+
+type goalRequest struct {
+	Kind   string `json:"kind"`
+	Target int    `json:"target"`
+}
+
+type goalItem struct {
+	ID        int64  `json:"id"`
+	Kind      string `json:"kind"`
+	Target    int    `json:"target"`
+	CreatedAt string `json:"created_at"`
+}
+
+func (req *goalRequest) validate() error {
+	req.Kind = strings.TrimSpace(req.Kind)
+	switch req.Kind {
+	case goalBooksPerYear, goalMinutesPerDay, goalPagesPerWeek:
+	case "":
+		return ErrRequired.Field("kind", "kind is required")
+	default:
+		return ErrInvalidValue.Field("kind", "kind must be one of books_per_year, minutes_per_day, pages_per_week")
+	}
+	if req.Target <= 0 {
+		return ErrInvalidValue.Field("target", "target must be > 0")
+	}
+	return nil
+}
+
+func (a *App) createGoal(w http.ResponseWriter, r *http.Request) {
+	var req goalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, ErrInvalidJSON)
+		return
+	}
+	if err := req.validate(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	uid := userID(r.Context())
+	out := goalItem{Kind: req.Kind, Target: req.Target, CreatedAt: timeOrNowRFC3339(nil)}
+	err := a.Store.WithTx(r.Context(), func(ctx context.Context, tx Querier) error {
+		var exists int
+		err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM goals WHERE user_id = ? AND kind = ?`, uid, req.Kind).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if exists > 0 {
+			return ErrGoalExists
+		}
+		return tx.QueryRow(ctx, `
+			INSERT INTO goals (user_id, kind, target, created_at)
+			VALUES (?, ?, ?, ?)
+			RETURNING id
+		`, uid, out.Kind, out.Target, out.CreatedAt).Scan(&out.ID)
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, out)
+}
+
+func (a *App) listGoals(w http.ResponseWriter, r *http.Request) {
+	rows, err := a.Store.Query(r.Context(), `SELECT id, kind, target, created_at FROM goals WHERE user_id = ? ORDER BY id`, userID(r.Context()))
+	if err != nil {
+		serverError(w, r, err, "query failed")
+		return
+	}
+	defer rows.Close()
+
+	items := make([]goalItem, 0)
+	for rows.Next() {
+		var it goalItem
+		if err := rows.Scan(&it.ID, &it.Kind, &it.Target, &it.CreatedAt); err != nil {
+			serverError(w, r, err, "scan failed")
+			return
+		}
+		items = append(items, it)
+	}
+	if err := rows.Err(); err != nil {
+		serverError(w, r, err, "row error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"items": items,
+		"meta":  map[string]any{"count": len(items)},
+	})
+}
+
+func (a *App) getGoal(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	it, err := findGoal(r.Context(), a.Store, userID(r.Context()), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, r, ErrGoalNotFound)
+		return
+	}
+	if err != nil {
+		serverError(w, r, err, "query failed")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, it)
+}
+
+func (a *App) updateGoal(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	var req goalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, ErrInvalidJSON)
+		return
+	}
+	if err := req.validate(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	uid := userID(r.Context())
+	var out goalItem
+	err = a.Store.WithTx(r.Context(), func(ctx context.Context, tx Querier) error {
+		var clash int
+		err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM goals WHERE user_id = ? AND kind = ? AND id <> ?`, uid, req.Kind, id).Scan(&clash)
+		if err != nil {
+			return err
+		}
+		if clash > 0 {
+			return ErrGoalExists
+		}
+		res, err := tx.Exec(ctx, `UPDATE goals SET kind = ?, target = ? WHERE id = ? AND user_id = ?`, req.Kind, req.Target, id, uid)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ErrGoalNotFound
+		}
+		return tx.QueryRow(ctx, `SELECT id, kind, target, created_at FROM goals WHERE id = ?`, id).
+			Scan(&out.ID, &out.Kind, &out.Target, &out.CreatedAt)
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (a *App) deleteGoal(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	res, err := a.Store.Exec(r.Context(), `DELETE FROM goals WHERE id = ? AND user_id = ?`, id, userID(r.Context()))
+	if err != nil {
+		serverError(w, r, err, "internal error")
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		writeError(w, r, ErrGoalNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func findGoal(ctx context.Context, q Querier, userID, id int64) (goalItem, error) {
+	var it goalItem
+	err := q.QueryRow(ctx, `SELECT id, kind, target, created_at FROM goals WHERE id = ? AND user_id = ?`, id, userID).
+		Scan(&it.ID, &it.Kind, &it.Target, &it.CreatedAt)
+	return it, err
+}
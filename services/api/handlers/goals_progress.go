@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type goalProgress struct {
+	GoalID       int64   `json:"goal_id"`
+	Kind         string  `json:"kind"`
+	Unit         string  `json:"unit"`
+	Target       int     `json:"target"`
+	PeriodStart  string  `json:"period_start"`
+	PeriodEnd    string  `json:"period_end"`
+	Actual       float64 `json:"actual"`
+	Remaining    float64 `json:"remaining"`
+	Percent      float64 `json:"percent"`
+	Achieved     bool    `json:"achieved"`
+	DaysLeft     int     `json:"days_left"`
+	NeededPerDay float64 `json:"needed_per_day"`
+}
+
+// goalSummary is the short form carried on stopSession responses.
+type goalSummary struct {
+	GoalID    int64   `json:"goal_id"`
+	Kind      string  `json:"kind"`
+	Unit      string  `json:"unit"`
+	Target    int     `json:"target"`
+	Actual    float64 `json:"actual"`
+	Remaining float64 `json:"remaining"`
+	Achieved  bool    `json:"achieved"`
+}
+
+func (a *App) getGoalProgress(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	uid := userID(r.Context())
+	g, err := findGoal(r.Context(), a.Store, uid, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, r, ErrGoalNotFound)
+		return
+	}
+	if err != nil {
+		serverError(w, r, err, "query failed")
+		return
+	}
+
+	loc, err := a.goalLocation(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	p, err := computeGoalProgress(r.Context(), a.Store, uid, g, time.Now(), loc)
+	if err != nil {
+		serverError(w, r, err, "query failed")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, p)
+}
+
+// goalLocation is the time zone goal periods are counted in: ?tz= if given,
+// else the registered time zone of the device the token is bound to, else
+// UTC.
+func (a *App) goalLocation(r *http.Request) (*time.Location, error) {
+	if v := strings.TrimSpace(r.URL.Query().Get("tz")); v != "" {
+		loc, err := time.LoadLocation(v)
+		if err != nil {
+			return nil, ErrInvalidTimeZone.Field("tz", "tz must be an IANA time zone (e.g., Europe/Berlin)")
+		}
+		return loc, nil
+	}
+	if d := boundDevice(r.Context()); d != "" {
+		return deviceLocation(r.Context(), a.Store, userID(r.Context()), d)
+	}
+	return time.UTC, nil
+}
+
+// goalPeriod returns the current period [start, end) in loc for a goal
+// kind: the calendar year, the day, or the ISO week starting on Monday.
+func goalPeriod(kind string, now time.Time, loc *time.Location) (start, end time.Time) {
+	now = now.In(loc)
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	switch kind {
+	case goalBooksPerYear:
+		start = time.Date(now.Year(), 1, 1, 0, 0, 0, 0, loc)
+		return start, start.AddDate(1, 0, 0)
+	case goalPagesPerWeek:
+		offset := (int(day.Weekday()) + 6) % 7 // days since Monday
+		start = day.AddDate(0, 0, -offset)
+		return start, start.AddDate(0, 0, 7)
+	default:
+		return day, day.AddDate(0, 0, 1)
+	}
+}
+
+// computeGoalProgress measures g over its current period in loc. Session
+// times are stored in UTC, so the period bounds are compared in UTC too.
+func computeGoalProgress(ctx context.Context, q Querier, userID int64, g goalItem, now time.Time, loc *time.Location) (_ goalProgress, err error) {
+	ctx, span := startSpan(ctx, "computeGoalProgress")
+	defer func() { endSpan(span, err) }()
+
+	start, end := goalPeriod(g.Kind, now, loc)
+	p := goalProgress{
+		GoalID:      g.ID,
+		Kind:        g.Kind,
+		Target:      g.Target,
+		PeriodStart: start.Format(time.RFC3339),
+		PeriodEnd:   end.Format(time.RFC3339),
+	}
+	args := []any{userID, start.UTC().Format(time.RFC3339), end.UTC().Format(time.RFC3339)}
+
+	switch g.Kind {
+	case goalBooksPerYear:
+		p.Unit = "books"
+		// A book counts once it has a session ending on or past its last page.
+		err = q.QueryRow(ctx, `
+SELECT COUNT(DISTINCT s.book_id)
+FROM sessions s
+JOIN books b ON b.id = s.book_id
+WHERE s.user_id = ? AND s.ended_at >= ? AND s.ended_at < ?
+  AND b.total_pages IS NOT NULL
+  AND s.end_page >= b.total_pages`, args...).Scan(&p.Actual)
+	case goalMinutesPerDay:
+		p.Unit = "minutes"
+		err = q.QueryRow(ctx, `
+SELECT COALESCE(SUM(duration_seconds), 0) / 60.0
+FROM sessions
+WHERE user_id = ? AND ended_at >= ? AND ended_at < ?`, args...).Scan(&p.Actual)
+	case goalPagesPerWeek:
+		p.Unit = "pages"
+		err = q.QueryRow(ctx, `
+SELECT COALESCE(SUM(end_page - start_page), 0)
+FROM sessions
+WHERE user_id = ? AND ended_at >= ? AND ended_at < ?
+  AND end_page IS NOT NULL
+  AND end_page >= start_page`, args...).Scan(&p.Actual)
+	}
+	if err != nil {
+		return p, err
+	}
+
+	p.Actual = round2(p.Actual)
+	p.Remaining = round2(math.Max(float64(g.Target)-p.Actual, 0))
+	p.Percent = round2(math.Min(p.Actual/float64(g.Target)*100, 100))
+	p.Achieved = p.Remaining == 0
+	p.DaysLeft = int(math.Ceil(end.Sub(now).Hours() / 24))
+	if p.DaysLeft > 0 {
+		p.NeededPerDay = round2(p.Remaining / float64(p.DaysLeft))
+	}
+	return p, nil
+}
+
+// goalSummaries computes progress for every goal of a user in loc, for
+// responses that only need the headline numbers.
+func goalSummaries(ctx context.Context, q Querier, userID int64, now time.Time, loc *time.Location) (_ []goalSummary, err error) {
+	ctx, span := startSpan(ctx, "goalSummaries")
+	defer func() { endSpan(span, err) }()
+
+	rows, err := q.Query(ctx, `SELECT id, kind, target, created_at FROM goals WHERE user_id = ? ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	var goals []goalItem
+	for rows.Next() {
+		var g goalItem
+		if err := rows.Scan(&g.ID, &g.Kind, &g.Target, &g.CreatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		goals = append(goals, g)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	out := make([]goalSummary, 0, len(goals))
+	for _, g := range goals {
+		p, err := computeGoalProgress(ctx, q, userID, g, now, loc)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, goalSummary{
+			GoalID:    p.GoalID,
+			Kind:      p.Kind,
+			Unit:      p.Unit,
+			Target:    p.Target,
+			Actual:    p.Actual,
+			Remaining: p.Remaining,
+			Achieved:  p.Achieved,
+		})
+	}
+	return out, nil
+}
@@ -0,0 +1,136 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestGoals_CRUD(t *testing.T) {
+	r := newTestServer(t)
+
+	w := doJSON(t, r, http.MethodPost, "/v1/goals", map[string]any{"kind": "pages_per_week", "target": 200})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create expected 201, got %d body=%s", w.Code, w.Body.String())
+	}
+
+	w = doJSON(t, r, http.MethodPost, "/v1/goals", map[string]any{"kind": "pages_per_week", "target": 100})
+	if w.Code != http.StatusConflict {
+		t.Fatalf("duplicate kind expected 409, got %d body=%s", w.Code, w.Body.String())
+	}
+
+	w = doJSON(t, r, http.MethodPost, "/v1/goals", map[string]any{"kind": "chapters_per_day", "target": 1})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("bad kind expected 400, got %d body=%s", w.Code, w.Body.String())
+	}
+
+	w = doJSON(t, r, http.MethodPut, "/v1/goals/1", map[string]any{"kind": "pages_per_week", "target": 250})
+	if w.Code != http.StatusOK {
+		t.Fatalf("update expected 200, got %d body=%s", w.Code, w.Body.String())
+	}
+	var g map[string]any
+	_ = json.Unmarshal(w.Body.Bytes(), &g)
+	if g["target"] != float64(250) {
+		t.Fatalf("target mismatch: %#v", g["target"])
+	}
+
+	w = doJSON(t, r, http.MethodDelete, "/v1/goals/1", nil)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("delete expected 204, got %d body=%s", w.Code, w.Body.String())
+	}
+	w = doJSON(t, r, http.MethodGet, "/v1/goals/1", nil)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("get after delete expected 404, got %d", w.Code)
+	}
+}
+
+func TestGoals_ProgressAndStopSummary(t *testing.T) {
+	r := newTestServer(t)
+
+	w := doJSON(t, r, http.MethodPost, "/v1/goals", map[string]any{"kind": "minutes_per_day", "target": 30})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create expected 201, got %d body=%s", w.Code, w.Body.String())
+	}
+
+	w = doJSON(t, r, http.MethodPost, "/v1/session/start", map[string]any{
+		"device_id":  "phone",
+		"book_title": "Dune",
+		"start_page": 0,
+		"started_at": time.Now().UTC().Add(-18 * time.Minute).Format(time.RFC3339),
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("start expected 201, got %d body=%s", w.Code, w.Body.String())
+	}
+	w = doJSON(t, r, http.MethodPost, "/v1/session/stop", map[string]any{"device_id": "phone", "end_page": 12})
+	if w.Code != http.StatusOK {
+		t.Fatalf("stop expected 200, got %d body=%s", w.Code, w.Body.String())
+	}
+
+	var stop struct {
+		GoalProgress []struct {
+			Kind      string  `json:"kind"`
+			Remaining float64 `json:"remaining"`
+		} `json:"goal_progress"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &stop)
+	if len(stop.GoalProgress) != 1 || stop.GoalProgress[0].Kind != "minutes_per_day" {
+		t.Fatalf("unexpected goal_progress: %s", w.Body.String())
+	}
+	if rem := stop.GoalProgress[0].Remaining; rem < 11.9 || rem > 12.1 {
+		t.Fatalf("expected ~12 minutes remaining, got %v", rem)
+	}
+
+	w = doJSON(t, r, http.MethodGet, "/v1/goals/1/progress", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("progress expected 200, got %d body=%s", w.Code, w.Body.String())
+	}
+	var p map[string]any
+	_ = json.Unmarshal(w.Body.Bytes(), &p)
+	if p["unit"] != "minutes" || p["achieved"] != false || p["days_left"] != float64(1) {
+		t.Fatalf("unexpected progress: %s", w.Body.String())
+	}
+}
+
+func TestGoals_PeriodFollowsTimeZone(t *testing.T) {
+	r := newTestServer(t)
+	doJSON(t, r, http.MethodPost, "/v1/goals", map[string]any{"kind": "minutes_per_day", "target": 30})
+	doJSON(t, r, http.MethodPost, "/v1/devices", map[string]any{"device_id": "kindle", "name": "Kindle", "type": "ereader", "timezone": "Pacific/Kiritimati"})
+
+	// 20 minutes on each side of midnight in Kiritimati (UTC+14). Both fall
+	// on the same UTC day, so only local days tell them apart.
+	loc, _ := time.LoadLocation("Pacific/Kiritimati")
+	now := time.Now().In(loc)
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	at := func(d time.Duration) string { return midnight.Add(d).UTC().Format(time.RFC3339) }
+	readSession(t, r, "kindle", "Dune", 0, 10, at(-30*time.Minute), at(-10*time.Minute))
+
+	w := doJSON(t, r, http.MethodPost, "/v1/session/start", map[string]any{"device_id": "kindle", "book_title": "Dune", "start_page": 10, "started_at": at(0)})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("start expected 201, got %d body=%s", w.Code, w.Body.String())
+	}
+	w = doJSON(t, r, http.MethodPost, "/v1/session/stop", map[string]any{"device_id": "kindle", "end_page": 20, "ended_at": at(20 * time.Minute)})
+	var stop struct {
+		GoalProgress []struct {
+			Actual float64 `json:"actual"`
+		} `json:"goal_progress"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &stop)
+	if len(stop.GoalProgress) != 1 || stop.GoalProgress[0].Actual != 20 {
+		t.Fatalf("stop should count the kindle's local day only: %s", w.Body.String())
+	}
+
+	w = doJSON(t, r, http.MethodGet, "/v1/goals/1/progress?tz=Pacific/Kiritimati", nil)
+	var p struct {
+		PeriodStart string  `json:"period_start"`
+		Actual      float64 `json:"actual"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &p)
+	if w.Code != http.StatusOK || p.PeriodStart != midnight.Format(time.RFC3339) || p.Actual != 20 {
+		t.Fatalf("expected today from %s with 20 minutes, got %d body=%s", midnight.Format(time.RFC3339), w.Code, w.Body.String())
+	}
+
+	if w := doJSON(t, r, http.MethodGet, "/v1/goals/1/progress?tz=Mars/Olympus", nil); w.Code != http.StatusBadRequest {
+		t.Fatalf("bad tz expected 400, got %d body=%s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// coreDataEpoch is 2001-01-01T00:00:00Z, which Apple Books' Core Data
+// timestamps count seconds from.
+const coreDataEpoch = 978307200
+
+// AppleBooksResult counts what ImportAppleBooks did with the library's books.
+type AppleBooksResult struct {
+	Created, Updated, Skipped int
+}
+
+// appleBooksAsset is one row of ZBKLIBRARYASSET.
+type appleBooksAsset struct {
+	AssetID, Title string
+	Author         *string
+	Progress       *float64 // 0-100
+	LastOpenedAt   *string
+	Finished       bool
+	FinishedOn     *string
+}
+
+// ImportAppleBooks imports the books in a copy of an Apple Books library
+// database (BKLibrary-*.sqlite, from ~/Library/Containers/com.apple.iBooksX/
+// Data/Documents/BKLibrary on a Mac) into user's library. Books are matched
+// by asset id, then by title; matched books are linked to their asset and
+// get Apple Books' reading progress and last-opened time, and details they
+// lack. The file is only read.
+func ImportAppleBooks(ctx context.Context, store Store, user, path string) (AppleBooksResult, error) {
+	var res AppleBooksResult
+	assets, err := readAppleBooks(ctx, path)
+	if err != nil {
+		return res, err
+	}
+
+	var uid int64
+	err = store.QueryRow(ctx, `SELECT id FROM users WHERE name = ?`, user).Scan(&uid)
+	if errors.Is(err, sql.ErrNoRows) {
+		return res, fmt.Errorf("%w: %q", ErrUserNotFound, user)
+	}
+	if err != nil {
+		return res, err
+	}
+
+	err = store.WithTx(ctx, func(ctx context.Context, tx Querier) error {
+		res = AppleBooksResult{}
+		now := timeOrNowRFC3339(nil)
+		for _, a := range assets {
+			if a.AssetID == "" || a.Title == "" {
+				res.Skipped++
+				continue
+			}
+			id, _, err := findBookByAssetID(ctx, tx, uid, a.AssetID)
+			if err != nil {
+				return err
+			}
+			if id == 0 {
+				if id, err = findBookIDByTitle(ctx, tx, uid, a.Title); err != nil {
+					return err
+				}
+			}
+			if id == 0 {
+				source := "apple_books"
+				if id, err = insertBook(ctx, tx, uid, a.Title, a.Author, &source, nil, now); err != nil {
+					return err
+				}
+				res.Created++
+			} else {
+				if _, err := tx.Exec(ctx, `UPDATE books SET author = COALESCE(author, ?) WHERE id = ?`, a.Author, id); err != nil {
+					return err
+				}
+				res.Updated++
+			}
+
+			c := bookCatalog{AssetID: &a.AssetID, FinishedOn: a.FinishedOn}
+			switch {
+			case a.Finished:
+				c.ReadStatus = ptr(readStatusRead)
+			case a.Progress != nil && *a.Progress > 0:
+				c.ReadStatus = ptr(readStatusReading)
+			}
+			if err := setBookCatalog(ctx, tx, id, c, true); err != nil {
+				return err
+			}
+			// Apple Books knows the current progress better than we do.
+			if _, err := tx.Exec(ctx, `
+				UPDATE books
+				SET reading_progress = COALESCE(?, reading_progress), last_opened_at = COALESCE(?, last_opened_at)
+				WHERE id = ?
+			`, a.Progress, a.LastOpenedAt, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return res, err
+}
+
+// readAppleBooks reads the assets in an Apple Books library database. Only
+// ZASSETID and ZTITLE are required; columns older or newer versions of
+// Apple Books lack are read as empty.
+func readAppleBooks(ctx context.Context, path string) ([]appleBooksAsset, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var cols []string
+	rows, err := db.QueryContext(ctx, `SELECT name FROM pragma_table_info('ZBKLIBRARYASSET')`)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c string
+		if err := rows.Scan(&c); err != nil {
+			return nil, err
+		}
+		cols = append(cols, strings.ToUpper(c))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if !slices.Contains(cols, "ZASSETID") || !slices.Contains(cols, "ZTITLE") {
+		return nil, fmt.Errorf("%s is not an Apple Books library: no ZBKLIBRARYASSET table with ZASSETID and ZTITLE", path)
+	}
+	col := func(name string) string {
+		if slices.Contains(cols, name) {
+			return name
+		}
+		return "NULL"
+	}
+
+	rows, err = db.QueryContext(ctx, `
+		SELECT ZASSETID, ZTITLE, `+col("ZAUTHOR")+`, `+col("ZREADINGPROGRESS")+`, `+col("ZLASTOPENDATE")+`,
+			`+col("ZISFINISHED")+`, `+col("ZDATEFINISHED")+`
+		FROM ZBKLIBRARYASSET
+		ORDER BY rowid`)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	defer rows.Close()
+
+	var out []appleBooksAsset
+	for rows.Next() {
+		var (
+			assetID, title, author   sql.NullString
+			progress, opened, doneAt sql.NullFloat64
+			finished                 sql.NullInt64
+		)
+		if err := rows.Scan(&assetID, &title, &author, &progress, &opened, &finished, &doneAt); err != nil {
+			return nil, err
+		}
+		a := appleBooksAsset{
+			AssetID:  strings.TrimSpace(assetID.String),
+			Title:    strings.TrimSpace(title.String),
+			Author:   optional(strings.TrimSpace(author.String)),
+			Finished: finished.Int64 != 0,
+		}
+		if progress.Valid {
+			p := math.Round(min(max(progress.Float64, 0), 1)*1000) / 10
+			a.Progress = &p
+		}
+		if opened.Valid && opened.Float64 > 0 {
+			a.LastOpenedAt = ptr(coreDataTime(opened.Float64).Format(time.RFC3339))
+		}
+		if doneAt.Valid && doneAt.Float64 > 0 {
+			a.FinishedOn = ptr(coreDataTime(doneAt.Float64).Format(time.DateOnly))
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+func coreDataTime(sec float64) time.Time {
+	return time.Unix(coreDataEpoch+int64(sec), 0).UTC()
+}
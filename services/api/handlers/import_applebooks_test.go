@@ -0,0 +1,117 @@
+package handlers_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/mk-slmn/booksmart/services/api/handlers"
+)
+
+// appleBooksLibrary writes a minimal BKLibrary database. Dates are Core
+// Data seconds since 2001-01-01: 780000000 is 2025-09-19T18:40:00Z.
+func appleBooksLibrary(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "BKLibrary-1-091020131601.sqlite")
+	db, err := sql.Open("sqlite", "file:"+path)
+	if err != nil {
+		t.Fatalf("open library: %v", err)
+	}
+	defer db.Close()
+	for _, q := range []string{
+		`CREATE TABLE ZBKLIBRARYASSET (Z_PK INTEGER PRIMARY KEY, ZASSETID VARCHAR, ZTITLE VARCHAR, ZAUTHOR VARCHAR,
+			ZREADINGPROGRESS FLOAT, ZLASTOPENDATE TIMESTAMP, ZISFINISHED INTEGER, ZDATEFINISHED TIMESTAMP)`,
+		`INSERT INTO ZBKLIBRARYASSET VALUES (1, '1E0F2A', 'Dune', 'Frank Herbert', 0.425, 780000000, 0, NULL)`,
+		`INSERT INTO ZBKLIBRARYASSET VALUES (2, '9A7C31', 'Emma', 'Jane Austen', 1.0, 779000000, 1, 779000000)`,
+		`INSERT INTO ZBKLIBRARYASSET VALUES (3, NULL, 'Sample', NULL, NULL, NULL, 0, NULL)`,
+	} {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatalf("build library: %v", err)
+		}
+	}
+	return path
+}
+
+func TestImportAppleBooks(t *testing.T) {
+	store := newTestDB(t)
+	r := newTestRouter(store)
+	// Dune is already in the library from a manual session.
+	readSession(t, r, "ipad", "Dune", 0, 20, "2025-09-01T10:00:00Z", "2025-09-01T10:30:00Z")
+
+	path := appleBooksLibrary(t)
+	res, err := handlers.ImportAppleBooks(context.Background(), store, "default", path)
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if res != (handlers.AppleBooksResult{Created: 1, Updated: 1, Skipped: 1}) {
+		t.Fatalf("unexpected counts %+v", res)
+	}
+
+	type book struct {
+		Title           string  `json:"title"`
+		Author          string  `json:"author"`
+		AssetID         string  `json:"asset_id"`
+		ReadingProgress float64 `json:"reading_progress"`
+		LastOpenedAt    string  `json:"last_opened_at"`
+		ReadStatus      string  `json:"read_status"`
+		FinishedOn      string  `json:"finished_on"`
+	}
+	var dune, emma book
+	_ = json.Unmarshal(doJSON(t, r, http.MethodGet, "/v1/books/1", nil).Body.Bytes(), &dune)
+	_ = json.Unmarshal(doJSON(t, r, http.MethodGet, "/v1/books/2", nil).Body.Bytes(), &emma)
+	if dune.Author != "Frank Herbert" || dune.AssetID != "1E0F2A" || dune.ReadingProgress != 42.5 ||
+		dune.LastOpenedAt != "2025-09-19T18:40:00Z" || dune.ReadStatus != "reading" {
+		t.Fatalf("Dune not linked to its asset: %+v", dune)
+	}
+	if emma.AssetID != "9A7C31" || emma.ReadingProgress != 100 || emma.ReadStatus != "read" || emma.FinishedOn == "" {
+		t.Fatalf("Emma not imported as finished: %+v", emma)
+	}
+
+	// Importing again only updates.
+	if res, err = handlers.ImportAppleBooks(context.Background(), store, "default", path); err != nil || res.Created != 0 || res.Updated != 2 {
+		t.Fatalf("re-import should only update, got %+v err=%v", res, err)
+	}
+
+	if _, err := handlers.ImportAppleBooks(context.Background(), store, "nobody", path); err == nil {
+		t.Fatalf("importing for an unknown user should fail")
+	}
+}
+
+func TestSessionStart_MatchesBookByAssetID(t *testing.T) {
+	store := newTestDB(t)
+	r := newTestRouter(store)
+	if _, err := handlers.ImportAppleBooks(context.Background(), store, "default", appleBooksLibrary(t)); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+
+	// The asset id wins over a differing title.
+	w := doJSON(t, r, http.MethodPost, "/v1/session/start", map[string]any{"device_id": "mac", "asset_id": "9A7C31", "book_title": "Emma (Penguin Classics)", "start_page": 0})
+	var started struct {
+		BookID    int64  `json:"book_id"`
+		BookTitle string `json:"book_title"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &started)
+	if w.Code != http.StatusCreated || started.BookID != 2 || started.BookTitle != "Emma" {
+		t.Fatalf("expected the session on Emma, got %d body=%s", w.Code, w.Body.String())
+	}
+
+	// An unknown asset id needs a title, and links the book it creates.
+	w = doJSON(t, r, http.MethodPost, "/v1/session/start", map[string]any{"device_id": "iphone", "asset_id": "55B0D4", "start_page": 0})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without a title, got %d body=%s", w.Code, w.Body.String())
+	}
+	w = doJSON(t, r, http.MethodPost, "/v1/session/start", map[string]any{"device_id": "iphone", "asset_id": "55B0D4", "book_title": "Middlemarch", "start_page": 0})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d body=%s", w.Code, w.Body.String())
+	}
+	var mm struct {
+		AssetID string `json:"asset_id"`
+	}
+	_ = json.Unmarshal(doJSON(t, r, http.MethodGet, "/v1/books/3", nil).Body.Bytes(), &mm)
+	if mm.AssetID != "55B0D4" {
+		t.Fatalf("Middlemarch should be linked to its asset, got %q", mm.AssetID)
+	}
+}
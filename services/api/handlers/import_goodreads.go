@@ -0,0 +1,311 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// importMaxBytes caps uploaded library exports.
+	importMaxBytes = 32 << 20
+
+	// goodreadsDevice is the device_id of the sessions a Goodreads import
+	// records for finished books.
+	goodreadsDevice = "goodreads"
+)
+
+// goodreadsStatus maps Goodreads' exclusive shelves to read statuses.
+var goodreadsStatus = map[string]string{
+	"to-read":           readStatusToRead,
+	"currently-reading": readStatusReading,
+	"read":              readStatusRead,
+}
+
+// goodreadsBook is one row of a Goodreads library export.
+type goodreadsBook struct {
+	Title      string
+	Author     *string
+	TotalPages *int
+	bookCatalog
+}
+
+// importRowError explains why a row of an uploaded file was not imported.
+type importRowError struct {
+	Row     int    `json:"row"`
+	Message string `json:"message"`
+}
+
+type goodreadsResult struct {
+	Rows     int              `json:"rows"`
+	Books    importCounts     `json:"books"`
+	Sessions importCounts     `json:"sessions"`
+	Errors   []importRowError `json:"errors"`
+}
+
+// uploadedFile returns the uploaded file: the multipart/form-data part named
+// "file", or else the raw request body. Reads past max fail with a
+// *http.MaxBytesError.
+func uploadedFile(w http.ResponseWriter, r *http.Request, max int64) (io.ReadCloser, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, max)
+	if ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); ct != "multipart/form-data" {
+		return r.Body, nil
+	}
+	f, _, err := r.FormFile("file")
+	if errors.Is(err, http.ErrMissingFile) {
+		return nil, ErrRequired.Field("file", "file is required")
+	}
+	return f, err
+}
+
+// uploadError maps errors from reading an upload to API errors; errors
+// that are not API errors mean the file is unreadable and become invalid.
+func uploadError(err error, max int64, invalid *Error) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return ErrTooLarge.WithMessage(fmt.Sprintf("uploads are limited to %d bytes", max))
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return err
+	}
+	return invalid.WithMessage(err.Error())
+}
+
+// importGoodreads imports a Goodreads library export ("Export Library" on
+// goodreads.com). Books are matched by title like startSession does: new
+// titles are created, known ones only get the details they lack. Each book
+// on the read shelf with a Date Read gets one closed session on the
+// "goodreads" device that day, so it counts as finished in the stats.
+// Rows that cannot be imported are listed in errors; the rest still are.
+func (a *App) importGoodreads(w http.ResponseWriter, r *http.Request) {
+	f, err := uploadedFile(w, r, importMaxBytes)
+	if err != nil {
+		writeError(w, r, uploadError(err, importMaxBytes, ErrInvalidCSV))
+		return
+	}
+	defer f.Close()
+
+	books, rowErrs, err := parseGoodreads(f)
+	if err != nil {
+		writeError(w, r, uploadError(err, importMaxBytes, ErrInvalidCSV))
+		return
+	}
+
+	ctx := r.Context()
+	uid := userID(ctx)
+	var res goodreadsResult
+	err = a.Store.WithTx(ctx, func(ctx context.Context, tx Querier) error {
+		res = goodreadsResult{Rows: len(books) + len(rowErrs), Errors: rowErrs}
+		now := timeOrNowRFC3339(nil)
+		for _, b := range books {
+			id, err := findBookIDByTitle(ctx, tx, uid, b.Title)
+			if err != nil {
+				return err
+			}
+			if id == 0 {
+				source := "goodreads"
+				if id, err = insertBook(ctx, tx, uid, b.Title, b.Author, &source, b.TotalPages, now); err != nil {
+					return err
+				}
+				res.Books.Created++
+			} else {
+				if _, err := tx.Exec(ctx, `UPDATE books SET author = COALESCE(author, ?), total_pages = COALESCE(total_pages, ?) WHERE id = ?`,
+					b.Author, b.TotalPages, id); err != nil {
+					return err
+				}
+				res.Books.Updated++
+			}
+			if err := setBookCatalog(ctx, tx, id, b.bookCatalog, true); err != nil {
+				return err
+			}
+
+			if b.ReadStatus == nil || *b.ReadStatus != readStatusRead || b.FinishedOn == nil {
+				continue
+			}
+			created, err := recordFinished(ctx, tx, uid, id, *b.FinishedOn, now)
+			if err != nil {
+				return err
+			}
+			if created {
+				res.Sessions.Created++
+			} else {
+				res.Sessions.Skipped++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	a.metrics.booksCreated.Add(float64(res.Books.Created))
+	if res.Errors == nil {
+		res.Errors = []importRowError{}
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// recordFinished adds the session standing for a book finished on day
+// (YYYY-MM-DD) according to an import: noon UTC, no duration, from page 0
+// to the book's last page. It reports false if the session already exists.
+func recordFinished(ctx context.Context, tx Querier, uid, bookID int64, day, now string) (bool, error) {
+	at := day + "T12:00:00Z"
+	var n int
+	if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM sessions WHERE book_id = ? AND device_id = ? AND started_at = ?`,
+		bookID, goodreadsDevice, at).Scan(&n); err != nil {
+		return false, err
+	}
+	if n > 0 {
+		return false, nil
+	}
+	var totalPages *int
+	if err := tx.QueryRow(ctx, `SELECT total_pages FROM books WHERE id = ?`, bookID).Scan(&totalPages); err != nil {
+		return false, err
+	}
+	var zero int64
+	_, err := insertImportedSession(ctx, tx, uid, bookID, goodreadsDevice, 0, totalPages, at, &at, &zero, now)
+	return err == nil, err
+}
+
+// parseGoodreads reads a Goodreads export. Rows without a title or with
+// unreadable values are returned as row errors; a file that is not CSV or
+// lacks a Title column is an error.
+func parseGoodreads(r io.Reader) ([]goodreadsBook, []importRowError, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, nil, ErrInvalidCSV.WithMessage("file is empty")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	col := make(map[string]int, len(header))
+	for i, h := range header {
+		col[strings.TrimSpace(strings.TrimPrefix(h, "\ufeff"))] = i
+	}
+	if _, ok := col["Title"]; !ok {
+		return nil, nil, ErrInvalidCSV.WithMessage("missing Title column; expected a Goodreads library export")
+	}
+
+	var books []goodreadsBook
+	var rowErrs []importRowError
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		row, _ := cr.FieldPos(0)
+		get := func(name string) string {
+			if i, ok := col[name]; ok && i < len(rec) {
+				return strings.TrimSpace(rec[i])
+			}
+			return ""
+		}
+
+		b, err := goodreadsRow(get)
+		if err != nil {
+			rowErrs = append(rowErrs, importRowError{Row: row, Message: err.Error()})
+			continue
+		}
+		books = append(books, b)
+	}
+	return books, rowErrs, nil
+}
+
+func goodreadsRow(get func(string) string) (goodreadsBook, error) {
+	b := goodreadsBook{Title: get("Title")}
+	if b.Title == "" {
+		return b, errors.New("Title is empty")
+	}
+	b.Author = optional(get("Author"))
+	if v := get("Number of Pages"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return b, fmt.Errorf("Number of Pages %q is not a page count", v)
+		}
+		if n > 0 {
+			b.TotalPages = &n
+		}
+	}
+
+	if v := get("My Rating"); v != "" && v != "0" {
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil || !validRating(n) {
+			return b, fmt.Errorf("My Rating %q is not 1 to 5 stars", v)
+		}
+		b.Rating = &n
+	}
+	b.Review = optional(strings.TrimSpace(goodreadsBreaks.Replace(get("My Review"))))
+
+	b.ISBN = goodreadsISBN(get("ISBN13"))
+	if b.ISBN == nil {
+		b.ISBN = goodreadsISBN(get("ISBN"))
+	}
+	shelf := get("Exclusive Shelf")
+	if s, ok := goodreadsStatus[shelf]; ok {
+		b.ReadStatus = &s
+	}
+	for _, s := range strings.Split(get("Bookshelves"), ",") {
+		if s = strings.TrimSpace(s); s != "" && !slices.Contains(b.Shelves, s) {
+			b.Shelves = append(b.Shelves, s)
+		}
+	}
+	if shelf != "" && !slices.Contains(b.Shelves, shelf) {
+		b.Shelves = append(b.Shelves, shelf)
+	}
+
+	var err error
+	if b.AddedOn, err = goodreadsDate(get("Date Added")); err != nil {
+		return b, fmt.Errorf("Date Added: %w", err)
+	}
+	if b.FinishedOn, err = goodreadsDate(get("Date Read")); err != nil {
+		return b, fmt.Errorf("Date Read: %w", err)
+	}
+	return b, nil
+}
+
+// goodreadsBreaks turns the HTML line breaks in Goodreads reviews into
+// newlines.
+var goodreadsBreaks = strings.NewReplacer("<br/>", "\n", "<br />", "\n", "<br>", "\n")
+
+// goodreadsISBN unwraps Goodreads' ="0441013597" spreadsheet quoting.
+func goodreadsISBN(v string) *string {
+	v = strings.Trim(v, `=" `)
+	return optional(v)
+}
+
+// goodreadsDate parses Goodreads' 2006/01/02 dates into YYYY-MM-DD.
+func goodreadsDate(v string) (*string, error) {
+	if v == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{"2006/01/02", time.DateOnly} {
+		if t, err := time.Parse(layout, v); err == nil {
+			d := t.Format(time.DateOnly)
+			return &d, nil
+		}
+	}
+	return nil, fmt.Errorf("%q is not a date like 2025/09/16", v)
+}
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,122 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const goodreadsCSV = `Book Id,Title,Author,Author l-f,Additional Authors,ISBN,ISBN13,My Rating,Average Rating,Publisher,Binding,Number of Pages,Year Published,Original Publication Year,Date Read,Date Added,Bookshelves,Bookshelves with positions,Exclusive Shelf,My Review,Spoiler,Private Notes,Read Count,Owned Copies
+234225,Dune,Frank Herbert,"Herbert, Frank",,"=""0441013597""","=""9780441013593""",5,4.27,Ace,Paperback,412,2005,1965,2025/03/14,2024/12/01,"sci-fi, favorites","sci-fi (#3), favorites (#1)",read,Still great.<br/>Worms!,,,1,0
+6185,Emma,Jane Austen,"Austen, Jane",,"=""""","=""""",0,4.03,Penguin,Paperback,474,2003,1815,,2025/01/05,,,to-read,,,,0,0
+1,,Nobody,,,,,0,0,,,,,,,2025/01/05,,,to-read,,,,0,0
+`
+
+type goodreadsResponse struct {
+	Rows            int
+	Books, Sessions struct{ Created, Updated, Skipped int }
+	Errors          []struct {
+		Row     int
+		Message string
+	}
+}
+
+func postGoodreads(t *testing.T, r http.Handler, csv string) goodreadsResponse {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/v1/import/goodreads", strings.NewReader(csv))
+	req.Header.Set("Content-Type", "text/csv")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("import expected 200, got %d body=%s", w.Code, w.Body.String())
+	}
+	var res goodreadsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatalf("decode import: %v", err)
+	}
+	return res
+}
+
+func TestImportGoodreads(t *testing.T) {
+	r := newTestServer(t)
+	// Dune is already in the library, without an author.
+	readSession(t, r, "ipad", "Dune", 0, 20, "2025-03-01T10:00:00Z", "2025-03-01T10:30:00Z")
+
+	res := postGoodreads(t, r, goodreadsCSV)
+	if res.Rows != 3 || res.Books.Created != 1 || res.Books.Updated != 1 || res.Sessions.Created != 1 {
+		t.Fatalf("unexpected counts %+v", res)
+	}
+	if len(res.Errors) != 1 || res.Errors[0].Row != 4 || !strings.Contains(res.Errors[0].Message, "Title") {
+		t.Fatalf("expected the untitled row 4 to be reported, got %+v", res.Errors)
+	}
+
+	w := doJSON(t, r, http.MethodGet, "/v1/books/1", nil)
+	var dune struct {
+		Title      string   `json:"title"`
+		Author     string   `json:"author"`
+		TotalPages int      `json:"total_pages"`
+		ISBN       string   `json:"isbn"`
+		ReadStatus string   `json:"read_status"`
+		Shelves    []string `json:"shelves"`
+		AddedOn    string   `json:"added_on"`
+		FinishedOn string   `json:"finished_on"`
+		Rating     float64  `json:"rating"`
+		Review     string   `json:"review"`
+		Sessions   int      `json:"sessions"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &dune)
+	if dune.Title != "Dune" || dune.Author != "Frank Herbert" || dune.TotalPages != 412 || dune.ISBN != "9780441013593" ||
+		dune.ReadStatus != "read" || strings.Join(dune.Shelves, "|") != "sci-fi|favorites|read" ||
+		dune.AddedOn != "2024-12-01" || dune.FinishedOn != "2025-03-14" || dune.Rating != 5 || dune.Review != "Still great.\nWorms!" ||
+		dune.Sessions != 2 {
+		t.Fatalf("Dune not merged with its Goodreads details: %s", w.Body.String())
+	}
+
+	w = doJSON(t, r, http.MethodGet, "/v1/stats/year/2025", nil)
+	var year struct {
+		BooksFinished int `json:"books_finished"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &year)
+	if year.BooksFinished != 1 {
+		t.Fatalf("the Date Read session should finish Dune, got %s", w.Body.String())
+	}
+
+	// Importing again only finds what it already created.
+	res = postGoodreads(t, r, goodreadsCSV)
+	if res.Books.Created != 0 || res.Books.Updated != 2 || res.Sessions.Created != 0 || res.Sessions.Skipped != 1 {
+		t.Fatalf("re-import should not duplicate anything, got %+v", res)
+	}
+}
+
+func TestImportGoodreads_MultipartAndBadFiles(t *testing.T) {
+	r := newTestServer(t)
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, _ := mw.CreateFormFile("file", "goodreads_library_export.csv")
+	_, _ = fw.Write([]byte(goodreadsCSV))
+	_ = mw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/v1/import/goodreads", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"created":2`) {
+		t.Fatalf("multipart upload expected 200 with 2 books, got %d body=%s", w.Code, w.Body.String())
+	}
+
+	for _, tc := range []struct{ name, body string }{
+		{"empty", ""},
+		{"not goodreads", "a,b\n1,2\n"},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/v1/import/goodreads", strings.NewReader(tc.body))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "invalid_csv") {
+			t.Errorf("%s: expected 400 invalid_csv, got %d body=%s", tc.name, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,341 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// kindleSeparator ends each entry of My Clippings.txt.
+const kindleSeparator = "=========="
+
+var (
+	kindleKind     = regexp.MustCompile(`(?i)^-\s*(?:your\s+)?(highlight|note|bookmark|clip)\b`)
+	kindlePage     = regexp.MustCompile(`(?i)\bpage\s+(\d+)`)
+	kindleLocation = regexp.MustCompile(`(?i)\b(?:location|loc\.)\s+(\d+)(?:-(\d+))?`)
+	kindleAdded    = regexp.MustCompile(`(?i)\badded on\s+(.+)$`)
+)
+
+// kindleTimeLayouts are the English "Added on" formats of US, UK and older
+// Kindles.
+var kindleTimeLayouts = []string{
+	"Monday, January 2, 2006 3:04:05 PM",
+	"Monday, 2 January 2006 15:04:05",
+	"Monday, January 2, 2006, 3:04 PM",
+	"Monday, 2 January 06 15:04:05",
+}
+
+// kindleClip is one highlight or note from My Clippings.txt.
+type kindleClip struct {
+	Title         string
+	Author        *string
+	Kind          string
+	Text          string
+	Page          *int
+	LocationStart *int
+	LocationEnd   *int
+	ClippedAt     *string
+}
+
+type kindleResult struct {
+	Entries    int              `json:"entries"`
+	Books      importCounts     `json:"books"`
+	Highlights importCounts     `json:"highlights"` // highlights and notes
+	Errors     []importRowError `json:"errors"`
+}
+
+// importKindle imports the highlights and notes in a Kindle's
+// documents/My Clippings.txt. Each book is matched by title (then by the
+// title without a trailing "(Series, Book 1)") or created, and clippings
+// already imported for it are skipped, so the growing file can be imported
+// again and again. Bookmarks are ignored. Kindles write local times without
+// a zone; ?tz= says which one (default UTC).
+func (a *App) importKindle(w http.ResponseWriter, r *http.Request) {
+	loc := time.UTC
+	if v := strings.TrimSpace(r.URL.Query().Get("tz")); v != "" {
+		l, err := time.LoadLocation(v)
+		if err != nil {
+			writeError(w, r, ErrInvalidTimeZone.Field("tz", "tz must be an IANA time zone (e.g., Europe/Berlin)"))
+			return
+		}
+		loc = l
+	}
+
+	f, err := uploadedFile(w, r, importMaxBytes)
+	if err != nil {
+		writeError(w, r, uploadError(err, importMaxBytes, ErrInvalidClips))
+		return
+	}
+	defer f.Close()
+
+	clips, entries, rowErrs, err := parseKindleClippings(f, loc)
+	if err != nil {
+		writeError(w, r, uploadError(err, importMaxBytes, ErrInvalidClips))
+		return
+	}
+
+	ctx := r.Context()
+	uid := userID(ctx)
+	var res kindleResult
+	err = a.Store.WithTx(ctx, func(ctx context.Context, tx Querier) error {
+		res = kindleResult{Entries: entries, Errors: rowErrs}
+		now := timeOrNowRFC3339(nil)
+		books := map[string]int64{}
+		for _, c := range clips {
+			id, ok := books[c.Title]
+			if !ok {
+				var created bool
+				var err error
+				if id, created, err = kindleBook(ctx, tx, uid, c, now); err != nil {
+					return err
+				}
+				if created {
+					res.Books.Created++
+				} else {
+					res.Books.Updated++
+				}
+				books[c.Title] = id
+			}
+
+			created, err := insertClipping(ctx, tx, uid, id, c, now)
+			if err != nil {
+				return err
+			}
+			if created {
+				res.Highlights.Created++
+			} else {
+				res.Highlights.Skipped++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	a.metrics.booksCreated.Add(float64(res.Books.Created))
+	if res.Errors == nil {
+		res.Errors = []importRowError{}
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// kindleBook finds or creates the book c belongs to, filling in its author
+// if it lacks one, and reports whether it was created.
+func kindleBook(ctx context.Context, tx Querier, uid int64, c kindleClip, now string) (int64, bool, error) {
+	id, err := findBookIDByTitle(ctx, tx, uid, c.Title)
+	if base := kindleBaseTitle(c.Title); err == nil && id == 0 && base != c.Title {
+		id, err = findBookIDByTitle(ctx, tx, uid, base)
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	if id != 0 {
+		_, err = tx.Exec(ctx, `UPDATE books SET author = COALESCE(author, ?) WHERE id = ?`, c.Author, id)
+		return id, false, err
+	}
+	source := "kindle"
+	id, err = insertBook(ctx, tx, uid, c.Title, c.Author, &source, nil, now)
+	return id, true, err
+}
+
+// insertClipping adds c to a book's notes unless an identical one is
+// there already, and reports whether it did.
+func insertClipping(ctx context.Context, tx Querier, uid, bookID int64, c kindleClip, now string) (created bool, err error) {
+	ctx, span := startSpan(ctx, "insertClipping")
+	defer func() { endSpan(span, err) }()
+
+	var n int
+	err = tx.QueryRow(ctx, `
+		SELECT COUNT(*) FROM notes
+		WHERE book_id = ? AND kind = ? AND text = ?
+			AND COALESCE(page, -1) = COALESCE(?, -1)
+			AND COALESCE(location_start, -1) = COALESCE(?, -1)
+			AND COALESCE(location_end, -1) = COALESCE(?, -1)
+			AND COALESCE(clipped_at, '') = COALESCE(?, '')
+	`, bookID, c.Kind, c.Text, c.Page, c.LocationStart, c.LocationEnd, c.ClippedAt).Scan(&n)
+	if err != nil || n > 0 {
+		return false, err
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO notes (user_id, book_id, kind, text, page, location_start, location_end, clipped_at, source, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, 'kindle', ?)
+	`, uid, bookID, c.Kind, c.Text, c.Page, c.LocationStart, c.LocationEnd, c.ClippedAt, now)
+	return err == nil, err
+}
+
+// parseKindleClippings reads My Clippings.txt: entries of a "Title (Author)"
+// line, a "- Your Highlight on page 12 | Location 170-172 | Added on …"
+// line, a blank line and the text, each ended by "==========". It returns
+// the highlights and notes, how many entries it saw and why the ones it
+// could not read were left out. A file without a single entry is an error.
+func parseKindleClippings(r io.Reader, loc *time.Location) ([]kindleClip, int, []importRowError, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64<<10), 1<<20)
+
+	var (
+		clips   []kindleClip
+		rowErrs []importRowError
+		entries int
+		entry   []string
+		first   int // line number of entry[0]
+		line    int
+	)
+	for sc.Scan() {
+		line++
+		text := strings.TrimRight(strings.ReplaceAll(sc.Text(), "\ufeff", ""), "\r")
+		if strings.TrimSpace(text) != kindleSeparator {
+			if len(entry) == 0 {
+				if strings.TrimSpace(text) == "" {
+					continue
+				}
+				first = line
+			}
+			entry = append(entry, text)
+			continue
+		}
+		if len(entry) == 0 {
+			continue
+		}
+		entries++
+		c, err := kindleEntry(entry, loc)
+		switch {
+		case err != nil:
+			rowErrs = append(rowErrs, importRowError{Row: first, Message: err.Error()})
+		case c.Kind != "":
+			clips = append(clips, c)
+		}
+		entry = entry[:0]
+	}
+	if err := sc.Err(); err != nil {
+		return nil, 0, nil, err
+	}
+	if entries == 0 {
+		return nil, 0, nil, ErrInvalidClips.WithMessage(`no entries ending in "==========" found`)
+	}
+	return clips, entries, rowErrs, nil
+}
+
+// kindleEntry parses the lines of one entry. Bookmarks come back with an
+// empty Kind.
+func kindleEntry(lines []string, loc *time.Location) (kindleClip, error) {
+	var c kindleClip
+	if len(lines) < 2 {
+		return c, errors.New("entry has no metadata line")
+	}
+	c.Title, c.Author = kindleTitle(lines[0])
+	if c.Title == "" {
+		return c, errors.New("entry has no title")
+	}
+
+	meta := strings.TrimSpace(lines[1])
+	m := kindleKind.FindStringSubmatch(meta)
+	if m == nil {
+		return c, fmt.Errorf("%q is not a highlight, note or bookmark line", meta)
+	}
+	switch strings.ToLower(m[1]) {
+	case "bookmark":
+		return c, nil
+	case "note":
+		c.Kind = noteKindNote
+	default:
+		c.Kind = noteKindHighlight
+	}
+
+	if m := kindlePage.FindStringSubmatch(meta); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		c.Page = &n
+	}
+	if m := kindleLocation.FindStringSubmatch(meta); m != nil {
+		start, _ := strconv.Atoi(m[1])
+		c.LocationStart = &start
+		if m[2] != "" {
+			end := kindleLocationEnd(m[1], m[2])
+			c.LocationEnd = &end
+		}
+	}
+	for _, part := range strings.Split(meta, "|") {
+		m := kindleAdded.FindStringSubmatch(strings.TrimSpace(part))
+		if m == nil {
+			continue
+		}
+		t, err := parseKindleTime(m[1], loc)
+		if err != nil {
+			return c, err
+		}
+		c.ClippedAt = ptr(t.UTC().Format(time.RFC3339))
+	}
+
+	c.Text = strings.TrimSpace(strings.Join(lines[2:], "\n"))
+	if c.Text == "" {
+		return c, fmt.Errorf("%s has no text", c.Kind)
+	}
+	if strings.HasPrefix(c.Text, "<You have exceeded the clipping limit") {
+		return c, errors.New("Kindle did not save this highlight: the book's clipping limit was reached")
+	}
+	return c, nil
+}
+
+// kindleTitle splits "Dune (Frank Herbert)" into title and author: the
+// author is the last parenthesized group, if the line ends in one.
+func kindleTitle(s string) (string, *string) {
+	s = strings.TrimSpace(s)
+	if !strings.HasSuffix(s, ")") {
+		return s, nil
+	}
+	depth := 0
+	for i := len(s) - 1; i >= 0; i-- {
+		switch s[i] {
+		case ')':
+			depth++
+		case '(':
+			depth--
+		}
+		if depth == 0 {
+			title := strings.TrimSpace(s[:i])
+			if title == "" {
+				return s, nil
+			}
+			return title, optional(strings.TrimSpace(s[i+1 : len(s)-1]))
+		}
+	}
+	return s, nil
+}
+
+// kindleBaseTitle drops a trailing "(Dune Chronicles, Book 1)" that Kindle
+// store titles carry and the library's titles may not.
+func kindleBaseTitle(title string) string {
+	base, series := kindleTitle(title)
+	if series == nil {
+		return title
+	}
+	return base
+}
+
+// kindleLocationEnd expands the abbreviated range ends of older Kindles
+// ("Loc. 1705-12" means 1705-1712).
+func kindleLocationEnd(start, end string) int {
+	if len(end) < len(start) {
+		end = start[:len(start)-len(end)] + end
+	}
+	n, _ := strconv.Atoi(end)
+	return n
+}
+
+func parseKindleTime(s string, loc *time.Location) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range kindleTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("Added on %q is not a date like Sunday, March 2, 2025 10:14:03 PM", s)
+}
@@ -0,0 +1,173 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const kindleClippings = "\ufeffDune (Dune Chronicles, Book 1) (Frank Herbert)\r\n" +
+	"- Your Highlight on page 8 | Location 170-172 | Added on Sunday, March 2, 2025 10:14:03 PM\r\n" +
+	"\r\n" +
+	"I must not fear. Fear is the mind-killer.\r\n" +
+	"==========\r\n" +
+	"\ufeffDune (Dune Chronicles, Book 1) (Frank Herbert)\r\n" +
+	"- Your Note on page 8 | Location 172 | Added on Sunday, March 2, 2025 10:15:00 PM\r\n" +
+	"\r\n" +
+	"Litany against fear\r\n" +
+	"==========\r\n" +
+	"\ufeffDune (Dune Chronicles, Book 1) (Frank Herbert)\r\n" +
+	"- Your Bookmark on page 20 | Location 301 | Added on Sunday, March 2, 2025 10:20:00 PM\r\n" +
+	"\r\n" +
+	"\r\n" +
+	"==========\r\n" +
+	"\ufeffEmma (Austen, Jane)\r\n" +
+	"- Highlight Loc. 1705-12 | Added on Monday, 3 March 2025 08:00:00\r\n" +
+	"\r\n" +
+	"Silly things do cease to be silly if they are done by sensible people.\r\n" +
+	"==========\r\n" +
+	"\ufeffEmma (Austen, Jane)\r\n" +
+	"- Your Highlight at location 90 | Added on Someday, soon\r\n" +
+	"\r\n" +
+	"Unreadable date.\r\n" +
+	"==========\r\n"
+
+type kindleResponse struct {
+	Entries           int
+	Books, Highlights struct{ Created, Updated, Skipped int }
+	Errors            []struct {
+		Row     int
+		Message string
+	}
+}
+
+func postKindle(t *testing.T, r http.Handler, query, body string) kindleResponse {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/v1/import/kindle"+query, strings.NewReader(body))
+	req.Header.Set("Content-Type", "text/plain")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("import expected 200, got %d body=%s", w.Code, w.Body.String())
+	}
+	var res kindleResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatalf("decode import: %v", err)
+	}
+	return res
+}
+
+func TestImportKindle(t *testing.T) {
+	r := newTestServer(t)
+	// Dune is already in the library under its plain title.
+	readSession(t, r, "kindle", "Dune", 0, 20, "2025-03-01T10:00:00Z", "2025-03-01T10:30:00Z")
+
+	res := postKindle(t, r, "?tz=Europe/Berlin", kindleClippings)
+	if res.Entries != 5 || res.Books.Created != 1 || res.Books.Updated != 1 || res.Highlights.Created != 3 {
+		t.Fatalf("unexpected counts %+v", res)
+	}
+	if len(res.Errors) != 1 || res.Errors[0].Row != 21 || !strings.Contains(res.Errors[0].Message, "Someday") {
+		t.Fatalf("expected the entry on line 21 to be reported, got %+v", res.Errors)
+	}
+
+	w := doJSON(t, r, http.MethodGet, "/v1/books/1/highlights", nil)
+	var list struct {
+		Items []struct {
+			Kind          string `json:"kind"`
+			Text          string `json:"text"`
+			Page          *int   `json:"page"`
+			LocationStart int    `json:"location_start"`
+			LocationEnd   *int   `json:"location_end"`
+			ClippedAt     string `json:"clipped_at"`
+		} `json:"items"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &list)
+	if len(list.Items) != 2 {
+		t.Fatalf("expected Dune's highlight and note, got %s", w.Body.String())
+	}
+	hl, note := list.Items[0], list.Items[1]
+	if hl.Kind != "highlight" || hl.Text != "I must not fear. Fear is the mind-killer." || hl.Page == nil || *hl.Page != 8 ||
+		hl.LocationStart != 170 || hl.LocationEnd == nil || *hl.LocationEnd != 172 || hl.ClippedAt != "2025-03-02T21:14:03Z" {
+		t.Fatalf("unexpected highlight: %s", w.Body.String())
+	}
+	if note.Kind != "note" || note.Text != "Litany against fear" || note.LocationEnd != nil {
+		t.Fatalf("unexpected note: %s", w.Body.String())
+	}
+
+	w = doJSON(t, r, http.MethodGet, "/v1/books/2/highlights?kind=highlight", nil)
+	if !strings.Contains(w.Body.String(), `"location_start":1705,"location_end":1712`) {
+		t.Fatalf("Emma's abbreviated location range not expanded: %s", w.Body.String())
+	}
+	w = doJSON(t, r, http.MethodGet, "/v1/books/2", nil)
+	if !strings.Contains(w.Body.String(), `"author":"Austen, Jane"`) || !strings.Contains(w.Body.String(), `"source":"kindle"`) {
+		t.Fatalf("Emma not created from its clipping: %s", w.Body.String())
+	}
+
+	// The file only grows; importing it again adds nothing.
+	res = postKindle(t, r, "?tz=Europe/Berlin", kindleClippings)
+	if res.Books.Created != 0 || res.Highlights.Created != 0 || res.Highlights.Skipped != 3 {
+		t.Fatalf("re-import should skip every clipping, got %+v", res)
+	}
+}
+
+func TestImportKindle_BadRequests(t *testing.T) {
+	r := newTestServer(t)
+	for _, tc := range []struct{ name, path, body, want string }{
+		{"empty", "/v1/import/kindle", "", "invalid_clippings"},
+		{"not clippings", "/v1/import/kindle", "just some notes\n", "invalid_clippings"},
+		{"bad tz", "/v1/import/kindle?tz=Mars/Olympus", kindleClippings, "invalid_timezone"},
+	} {
+		req := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.body))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), tc.want) {
+			t.Errorf("%s: expected 400 %s, got %d body=%s", tc.name, tc.want, w.Code, w.Body.String())
+		}
+	}
+
+	if w := doJSON(t, r, http.MethodGet, "/v1/books/99/highlights", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("highlights of a missing book: expected 404, got %d", w.Code)
+	}
+	readSession(t, r, "kindle", "Dune", 0, 20, "2025-03-01T10:00:00Z", "2025-03-01T10:30:00Z")
+	if w := doJSON(t, r, http.MethodGet, "/v1/books/1/highlights?kind=bookmark", nil); w.Code != http.StatusBadRequest {
+		t.Fatalf("unknown kind: expected 400, got %d", w.Code)
+	}
+}
+
+func TestImportKindle_HighlightsRouteListsOnlyKindleClippings(t *testing.T) {
+	r := newTestServer(t)
+	readSession(t, r, "kindle", "Dune", 0, 20, "2025-03-01T10:00:00Z", "2025-03-01T10:30:00Z")
+	postKindle(t, r, "", kindleClippings)
+
+	count := func(path string) int {
+		w := doJSON(t, r, http.MethodGet, path, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s expected 200, got %d body=%s", path, w.Code, w.Body.String())
+		}
+		var list struct {
+			Meta struct {
+				Count int `json:"count"`
+			} `json:"meta"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &list)
+		return list.Meta.Count
+	}
+	highlights, notes := count("/v1/books/1/highlights"), count("/v1/books/1/notes")
+
+	doJSON(t, r, http.MethodPost, "/v1/notes", map[string]any{"book_id": 1, "text": "My own thought."})
+	doJSON(t, r, http.MethodPost, "/v1/notes", map[string]any{"book_id": 1, "kind": "quote", "text": "A quote I typed."})
+	if n := count("/v1/books/1/notes"); n != notes+2 {
+		t.Fatalf("expected %d notes, got %d", notes+2, n)
+	}
+	if n := count("/v1/books/1/highlights"); n != highlights {
+		t.Fatalf("hand-written notes leaked into highlights: %d before, %d after", highlights, n)
+	}
+	if n := count("/v1/books/1/highlights?kind=note"); n != 1 {
+		t.Fatalf("expected only Dune's Kindle note, got %d", n)
+	}
+	if w := doJSON(t, r, http.MethodGet, "/v1/books/1/highlights?kind=quote", nil); w.Code != http.StatusBadRequest {
+		t.Fatalf("highlights never had quotes: expected 400, got %d", w.Code)
+	}
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// logEntry collects what handlers learn about a request — who made it, for
+// which device, and why it failed — for the single line requestLogger writes
+// when the request is done.
+type logEntry struct {
+	userID   int64
+	deviceID string
+	traceID  string
+	err      error
+}
+
+func entryFrom(ctx context.Context) *logEntry {
+	e, _ := ctx.Value(logCtxKey).(*logEntry)
+	return e
+}
+
+// logUser and logDevice record the request's user and device_id.
+func logUser(ctx context.Context, id int64) {
+	if e := entryFrom(ctx); e != nil {
+		e.userID = id
+	}
+}
+
+func logDevice(ctx context.Context, deviceID string) {
+	if e := entryFrom(ctx); e != nil {
+		e.deviceID = deviceID
+	}
+}
+
+// logError records err as the request's error without answering, for
+// failures after the response has started.
+func logError(ctx context.Context, err error) {
+	if e := entryFrom(ctx); e != nil {
+		e.err = err
+	}
+}
+
+// serverError answers 500 with msg and records err as the cause, so the
+// request's log line says what actually went wrong.
+func serverError(w http.ResponseWriter, r *http.Request, err error, msg string) {
+	if e := entryFrom(r.Context()); e != nil && err != nil {
+		e.err = err
+	}
+	writeJSON(w, http.StatusInternalServerError, map[string]any{
+		"error": map[string]any{
+			"code":    codeInternal,
+			"message": msg,
+		},
+	})
+}
+
+func (a *App) logger() *slog.Logger {
+	if a.Logger != nil {
+		return a.Logger
+	}
+	return slog.Default()
+}
+
+// requestLogger logs one line per request with the chi request ID, route,
+// status, latency and whatever handlers recorded in the request's logEntry.
+// Server errors are logged at error level with their cause.
+func (a *App) requestLogger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		entry := &logEntry{}
+		w.Header().Set(middleware.RequestIDHeader, middleware.GetReqID(r.Context()))
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		r = r.WithContext(context.WithValue(r.Context(), logCtxKey, entry))
+
+		next.ServeHTTP(ww, r)
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		route := ""
+		if rc := chi.RouteContext(r.Context()); rc != nil {
+			route = rc.RoutePattern()
+		}
+		attrs := []slog.Attr{
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			slog.String("method", r.Method),
+			slog.String("route", route),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", status),
+			slog.Int("bytes", ww.BytesWritten()),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+		}
+		if entry.userID != 0 {
+			attrs = append(attrs, slog.Int64("user_id", entry.userID))
+		}
+		if entry.deviceID != "" {
+			attrs = append(attrs, slog.String("device_id", entry.deviceID))
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+		if entry.traceID != "" {
+			attrs = append(attrs, slog.String("trace_id", entry.traceID))
+		}
+		if entry.err != nil {
+			attrs = append(attrs, slog.String("error", entry.err.Error()))
+		}
+		a.logger().LogAttrs(r.Context(), level, "request", attrs...)
+	})
+}
+
+// recoverer turns a panic into a 500, logging it with its stack.
+func (a *App) recoverer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if err, ok := rec.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				panic(rec)
+			}
+			a.logger().LogAttrs(r.Context(), slog.LevelError, "panic",
+				slog.String("request_id", middleware.GetReqID(r.Context())),
+				slog.Any("panic", rec),
+				slog.String("stack", string(debug.Stack())),
+			)
+			serverError(w, r, fmt.Errorf("panic: %v", rec), "internal error")
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,57 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/mk-slmn/booksmart/services/api/handlers"
+)
+
+func TestRequestLog_CarriesCorrelationAndCause(t *testing.T) {
+	var buf bytes.Buffer
+	store := newTestDB(t)
+	r := (&handlers.App{Store: store, Logger: slog.New(slog.NewJSONHandler(&buf, nil))}).Routes()
+
+	w := doJSON(t, r, http.MethodPost, "/v1/session/start", map[string]any{"device_id": "ipad", "book_title": "Dune"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("start expected 201, got %d", w.Code)
+	}
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("expected one JSON log line, got %q", buf.String())
+	}
+	if line["msg"] != "request" || line["level"] != "INFO" || line["route"] != "/v1/session/start" ||
+		line["device_id"] != "ipad" || line["status"] != float64(201) || line["request_id"] == "" {
+		t.Fatalf("unexpected log line: %v", line)
+	}
+	if w.Header().Get("X-Request-Id") != line["request_id"] {
+		t.Fatalf("expected X-Request-Id %v, got %q", line["request_id"], w.Header().Get("X-Request-Id"))
+	}
+	if _, ok := line["latency_ms"]; !ok {
+		t.Fatalf("expected latency_ms in %v", line)
+	}
+
+	// With the database gone the stop fails; the client gets a generic 500
+	// but the log line keeps the cause.
+	buf.Reset()
+	_ = store.Close()
+	w = doJSON(t, r, http.MethodPost, "/v1/session/stop", map[string]any{"device_id": "ipad"})
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("stop expected 500, got %d", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "closed") {
+		t.Fatalf("cause leaked to client: %s", w.Body.String())
+	}
+	line = nil
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("expected one JSON log line, got %q", buf.String())
+	}
+	if line["level"] != "ERROR" || !strings.Contains(line["error"].(string), "closed") {
+		t.Fatalf("expected error line with cause, got %v", line)
+	}
+}
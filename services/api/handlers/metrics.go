@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metrics holds an app's Prometheus collectors. Each App has its own
+// registry, so several servers (or tests) in one process do not clash.
+type metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+	dbDuration   *prometheus.HistogramVec
+	txRetries    prometheus.Counter
+
+	sessionsStarted *prometheus.CounterVec
+	sessionsStopped *prometheus.CounterVec
+	booksCreated    prometheus.Counter
+}
+
+func newMetrics(store Store) *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "booksmart_http_requests_total",
+			Help: "HTTP requests by route pattern, method and status code.",
+		}, []string{"route", "method", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "booksmart_http_request_duration_seconds",
+			Help:    "HTTP request latency by route pattern and method.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+		dbDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "booksmart_db_query_duration_seconds",
+			Help:    "Database call latency by operation (query, query_row, exec, tx).",
+			Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"op"}),
+		txRetries: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "booksmart_db_tx_retries_total",
+			Help: "Transactions retried because SQLite reported SQLITE_BUSY.",
+		}),
+		sessionsStarted: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "booksmart_sessions_started_total",
+			Help: "Reading sessions started, by endpoint (start or continue).",
+		}, []string{"via"}),
+		sessionsStopped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "booksmart_sessions_stopped_total",
+			Help: "Reading sessions closed, by endpoint (stop, or start closing the previous one).",
+		}, []string{"via"}),
+		booksCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "booksmart_books_created_total",
+			Help: "Books created by starting a session on a new title.",
+		}),
+	}
+
+	openSessions := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "booksmart_open_sessions",
+		Help: "Reading sessions currently open, across all users.",
+	}, func() float64 {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		var n int
+		if err := store.QueryRow(ctx, `SELECT COUNT(*) FROM sessions WHERE ended_at IS NULL`).Scan(&n); err != nil {
+			return -1
+		}
+		return float64(n)
+	})
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests, m.httpDuration, m.dbDuration, m.txRetries,
+		m.sessionsStarted, m.sessionsStopped, m.booksCreated, openSessions,
+	)
+	return m
+}
+
+func (m *metrics) handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// middleware records request counts and latency per chi route pattern, so
+// /v1/books/{id} is one series rather than one per book.
+func (m *metrics) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		route := "unmatched"
+		if rc := chi.RouteContext(r.Context()); rc != nil && rc.RoutePattern() != "" {
+			route = rc.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		m.httpRequests.WithLabelValues(route, r.Method, strconv.Itoa(status)).Inc()
+		m.httpDuration.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+	})
+}
+
+// instrument wraps store so every call is timed.
+func (m *metrics) instrument(store Store) Store {
+	return &instrumentedStore{Store: store, q: instrumentedQuerier{q: store, m: m}}
+}
+
+type instrumentedQuerier struct {
+	q Querier
+	m *metrics
+}
+
+func (q instrumentedQuerier) observe(op string, start time.Time) {
+	q.m.dbDuration.WithLabelValues(op).Observe(time.Since(start).Seconds())
+}
+
+func (q instrumentedQuerier) Query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	defer q.observe("query", time.Now())
+	return q.q.Query(ctx, query, args...)
+}
+
+func (q instrumentedQuerier) QueryRow(ctx context.Context, query string, args ...any) *sql.Row {
+	defer q.observe("query_row", time.Now())
+	return q.q.QueryRow(ctx, query, args...)
+}
+
+func (q instrumentedQuerier) Exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	defer q.observe("exec", time.Now())
+	return q.q.Exec(ctx, query, args...)
+}
+
+type instrumentedStore struct {
+	Store
+	q instrumentedQuerier
+}
+
+func (s *instrumentedStore) Query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return s.q.Query(ctx, query, args...)
+}
+
+func (s *instrumentedStore) QueryRow(ctx context.Context, query string, args ...any) *sql.Row {
+	return s.q.QueryRow(ctx, query, args...)
+}
+
+func (s *instrumentedStore) Exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return s.q.Exec(ctx, query, args...)
+}
+
+// WithTx times the whole transaction and the calls made inside it. fn runs
+// once per attempt, so every run after the first is a retry.
+func (s *instrumentedStore) WithTx(ctx context.Context, fn func(ctx context.Context, tx Querier) error) error {
+	defer s.q.observe("tx", time.Now())
+	attempts := 0
+	return s.Store.WithTx(ctx, func(ctx context.Context, tx Querier) error {
+		if attempts++; attempts > 1 {
+			s.q.m.txRetries.Inc()
+		}
+		return fn(ctx, instrumentedQuerier{q: tx, m: s.q.m})
+	})
+}
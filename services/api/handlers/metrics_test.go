@@ -0,0 +1,49 @@
+package handlers_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestMetrics_ExposesHTTPDatabaseAndDomainSeries(t *testing.T) {
+	r := newTestServer(t)
+
+	w := doJSON(t, r, http.MethodPost, "/v1/session/start", map[string]any{"device_id": "ipad", "book_title": "Dune"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("start expected 201, got %d", w.Code)
+	}
+	scrape := doJSON(t, r, http.MethodGet, "/metrics", nil).Body.String()
+	if !strings.Contains(scrape, "booksmart_open_sessions 1\n") {
+		t.Fatalf("expected one open session in:\n%s", scrape)
+	}
+
+	// Starting on the same device closes the first session.
+	doJSON(t, r, http.MethodPost, "/v1/session/start", map[string]any{"device_id": "ipad", "book_title": "Dune"})
+	doJSON(t, r, http.MethodPost, "/v1/session/stop", map[string]any{"device_id": "ipad", "end_page": 10})
+	doJSON(t, r, http.MethodGet, "/v1/books/1", nil)
+	doJSON(t, r, http.MethodGet, "/v1/books/2", nil)
+
+	w = doJSON(t, r, http.MethodGet, "/metrics", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("metrics expected 200, got %d", w.Code)
+	}
+	scrape = w.Body.String()
+	for _, want := range []string{
+		`booksmart_http_requests_total{method="POST",route="/v1/session/start",status="201"} 2`,
+		`booksmart_http_requests_total{method="GET",route="/v1/books/{id}",status="200"} 1`,
+		`booksmart_http_requests_total{method="GET",route="/v1/books/{id}",status="404"} 1`,
+		`booksmart_http_request_duration_seconds_count{method="POST",route="/v1/session/stop"} 1`,
+		`booksmart_db_query_duration_seconds_count{op="tx"} 3`,
+		`booksmart_db_tx_retries_total 0`,
+		`booksmart_sessions_started_total{via="start"} 2`,
+		`booksmart_sessions_stopped_total{via="start"} 1`,
+		`booksmart_sessions_stopped_total{via="stop"} 1`,
+		`booksmart_books_created_total 1`,
+		"booksmart_open_sessions 0\n",
+	} {
+		if !strings.Contains(scrape, want) {
+			t.Errorf("missing %s", want)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/sqlite/*.sql migrations/postgres/*.sql
+var migrationFS embed.FS
+
+// ErrSchemaTooNew means the database was migrated by a newer binary.
+var ErrSchemaTooNew = errors.New("database schema is newer than this binary")
+
+type migration struct {
+	Version int
+	Name    string
+	SQL     string
+
+	// AdoptIf is the query of an "-- adopt-if: SELECT …" line in the file.
+	// When it returns a non-zero count, what the migration creates is
+	// already there (made by a binary from before migrations existed), so it
+	// is recorded as applied without running.
+	AdoptIf string
+}
+
+const adoptIfPrefix = "-- adopt-if:"
+
+func parseAdoptIf(sql string) string {
+	for _, line := range strings.Split(sql, "\n") {
+		if q, ok := strings.CutPrefix(strings.TrimSpace(line), adoptIfPrefix); ok {
+			return strings.TrimSpace(q)
+		}
+	}
+	return ""
+}
+
+// loadMigrations reads the embedded NNNN_name.sql files for a dialect in
+// version order.
+func loadMigrations(dialect string) ([]migration, error) {
+	dir := path.Join("migrations", dialect)
+	entries, err := fs.ReadDir(migrationFS, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]migration, 0, len(entries))
+	seen := map[int]string{}
+	for _, e := range entries {
+		name := strings.TrimSuffix(e.Name(), ".sql")
+		num, _, ok := strings.Cut(name, "_")
+		v, err := strconv.Atoi(num)
+		if !ok || err != nil || v <= 0 {
+			return nil, fmt.Errorf("migration %q: name must look like 0001_description.sql", e.Name())
+		}
+		if prev, dup := seen[v]; dup {
+			return nil, fmt.Errorf("migrations %q and %q share version %d", prev, e.Name(), v)
+		}
+		seen[v] = e.Name()
+
+		body, err := fs.ReadFile(migrationFS, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, migration{Version: v, Name: name, SQL: string(body), AdoptIf: parseAdoptIf(string(body))})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+// SchemaVersion is the newest migration embedded in this binary. Both
+// dialects end on the same version.
+func SchemaVersion() int {
+	ms, err := loadMigrations(dialectSQLite)
+	if err != nil || len(ms) == 0 {
+		return 0
+	}
+	return ms[len(ms)-1].Version
+}
+
+// Migrate applies pending SQLite migrations, each in its own transaction together
+// with its schema_migrations row. It refuses to touch a database that has
+// migrations this binary does not know about.
+//
+// Foreign keys are switched off while migrating so tables can be rebuilt
+// (SQLite cannot drop a column constraint in place); each migration must
+// still pass PRAGMA foreign_key_check before it commits.
+func Migrate(db *sql.DB) error {
+	ms, err := loadMigrations(dialectSQLite)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	current, err := schemaVersion(ctx, conn, ms)
+	if err != nil {
+		return err
+	}
+	if current == ms[len(ms)-1].Version {
+		return nil
+	}
+
+	var fk int
+	if err := conn.QueryRowContext(ctx, `PRAGMA foreign_keys`).Scan(&fk); err != nil {
+		return err
+	}
+	if _, err := conn.ExecContext(ctx, `PRAGMA foreign_keys = OFF`); err != nil {
+		return err
+	}
+	defer func() { _, _ = conn.ExecContext(ctx, `PRAGMA foreign_keys = `+strconv.Itoa(fk)) }()
+
+	for _, m := range ms {
+		if m.Version <= current {
+			continue
+		}
+		if err := applyMigration(ctx, conn, dialectSQLite, m); err != nil {
+			return fmt.Errorf("migration %s: %w", m.Name, err)
+		}
+	}
+	return nil
+}
+
+// MigratePostgres applies pending PostgreSQL migrations, each in its own
+// transaction. An advisory lock keeps several servers starting at once from
+// migrating the same database twice.
+func MigratePostgres(db *sql.DB) error {
+	ms, err := loadMigrations(dialectPostgres)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrateLockID); err != nil {
+		return fmt.Errorf("lock schema_migrations: %w", err)
+	}
+	defer func() { _, _ = conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, migrateLockID) }()
+
+	current, err := schemaVersion(ctx, conn, ms)
+	if err != nil {
+		return err
+	}
+	for _, m := range ms {
+		if m.Version <= current {
+			continue
+		}
+		if err := applyMigration(ctx, conn, dialectPostgres, m); err != nil {
+			return fmt.Errorf("migration %s: %w", m.Name, err)
+		}
+	}
+	return nil
+}
+
+// migrateLockID is the advisory lock key MigratePostgres holds ("bsmt").
+const migrateLockID = 0x62736d74
+
+// schemaVersion creates schema_migrations if needed and returns the version
+// the database is at, refusing one newer than ms.
+func schemaVersion(ctx context.Context, conn *sql.Conn, ms []migration) (int, error) {
+	if _, err := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TEXT NOT NULL
+		)`); err != nil {
+		return 0, fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	var current int
+	if err := conn.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return 0, fmt.Errorf("read schema version: %w", err)
+	}
+	latest := 0
+	if len(ms) > 0 {
+		latest = ms[len(ms)-1].Version
+	}
+	if current > latest {
+		return 0, fmt.Errorf("%w: database is at version %d, binary knows up to %d", ErrSchemaTooNew, current, latest)
+	}
+	return current, nil
+}
+
+func applyMigration(ctx context.Context, conn *sql.Conn, dialect string, m migration) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	adopted := 0
+	if m.AdoptIf != "" {
+		if err := tx.QueryRowContext(ctx, m.AdoptIf).Scan(&adopted); err != nil {
+			return fmt.Errorf("adopt-if: %w", err)
+		}
+	}
+	if adopted == 0 {
+		if _, err := tx.ExecContext(ctx, m.SQL); err != nil {
+			return err
+		}
+	}
+
+	if dialect == dialectSQLite {
+		rows, err := tx.QueryContext(ctx, `PRAGMA foreign_key_check`)
+		if err != nil {
+			return err
+		}
+		broken := rows.Next()
+		rows.Close()
+		if broken {
+			return errors.New("foreign key check failed")
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, rebind(dialect, `
+		INSERT INTO schema_migrations (version, name, applied_at)
+		VALUES (?, ?, ?)
+	`), m.Version, m.Name, time.Now().UTC().Format(time.RFC3339)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
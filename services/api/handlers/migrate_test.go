@@ -0,0 +1,171 @@
+package handlers_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/mk-slmn/booksmart/services/api/handlers"
+)
+
+func TestMigrate_UpgradesPreMigrationDatabase(t *testing.T) {
+	db, err := sql.Open("sqlite", "file::memory:")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+
+	// Schema as created by the old single ReadSchema blob.
+	if _, err := db.Exec(`
+		CREATE TABLE books (
+			id INTEGER PRIMARY KEY,
+			title TEXT NOT NULL UNIQUE,
+			author TEXT,
+			source TEXT,
+			created_at TEXT NOT NULL DEFAULT (datetime('now'))
+		);
+		CREATE TABLE sessions (
+			id INTEGER PRIMARY KEY,
+			book_id INTEGER NOT NULL REFERENCES books(id),
+			device_id TEXT NOT NULL,
+			start_page INTEGER NOT NULL CHECK (start_page >= 0),
+			end_page INTEGER CHECK (end_page IS NULL OR end_page >= 0),
+			started_at TEXT NOT NULL,
+			ended_at TEXT,
+			duration_seconds INTEGER,
+			created_at TEXT NOT NULL DEFAULT (datetime('now'))
+		);
+		INSERT INTO books (title) VALUES ('Dune');
+		INSERT INTO sessions (book_id, device_id, start_page, started_at) VALUES (1, 'ipad', 0, '2025-09-16T20:00:00Z');
+	`); err != nil {
+		t.Fatalf("seed legacy schema: %v", err)
+	}
+
+	if err := handlers.Migrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	var title string
+	var totalPages *int
+	if err := db.QueryRow(`SELECT title, total_pages FROM books`).Scan(&title, &totalPages); err != nil {
+		t.Fatalf("books after migrate: %v", err)
+	}
+	if title != "Dune" || totalPages != nil {
+		t.Fatalf("unexpected row: %q %v", title, totalPages)
+	}
+	var owner int64
+	if err := db.QueryRow(`SELECT user_id FROM sessions WHERE book_id = 1`).Scan(&owner); err != nil || owner != 1 {
+		t.Fatalf("expected session owned by default user, got %d (%v)", owner, err)
+	}
+
+	var version int
+	_ = db.QueryRow(`SELECT MAX(version) FROM schema_migrations`).Scan(&version)
+	if version != handlers.SchemaVersion() {
+		t.Fatalf("expected version %d, got %d", handlers.SchemaVersion(), version)
+	}
+}
+
+func TestMigrate_AdoptsBootstrapSchema(t *testing.T) {
+	db, err := sql.Open("sqlite", "file::memory:")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+
+	// Schema as the ReadSchema blob created it once it knew total_pages and
+	// goals.
+	if _, err := db.Exec(`
+		CREATE TABLE books (
+			id INTEGER PRIMARY KEY,
+			title TEXT NOT NULL UNIQUE,
+			author TEXT,
+			source TEXT,
+			total_pages INTEGER CHECK (total_pages IS NULL OR total_pages > 0),
+			created_at TEXT NOT NULL DEFAULT (datetime('now'))
+		);
+		CREATE TABLE sessions (
+			id INTEGER PRIMARY KEY,
+			book_id INTEGER NOT NULL REFERENCES books(id),
+			device_id TEXT NOT NULL,
+			start_page INTEGER NOT NULL CHECK (start_page >= 0),
+			end_page INTEGER CHECK (end_page IS NULL OR end_page >= 0),
+			started_at TEXT NOT NULL,
+			ended_at TEXT,
+			duration_seconds INTEGER,
+			created_at TEXT NOT NULL DEFAULT (datetime('now'))
+		);
+		CREATE TABLE goals (
+			id INTEGER PRIMARY KEY,
+			kind TEXT NOT NULL UNIQUE CHECK (kind IN ('books_per_year', 'minutes_per_day', 'pages_per_week')),
+			target INTEGER NOT NULL CHECK (target > 0),
+			created_at TEXT NOT NULL DEFAULT (datetime('now'))
+		);
+		INSERT INTO books (title, total_pages) VALUES ('Dune', 412);
+		INSERT INTO goals (kind, target) VALUES ('books_per_year', 24);
+	`); err != nil {
+		t.Fatalf("seed bootstrap schema: %v", err)
+	}
+
+	if err := handlers.Migrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	var totalPages int
+	if err := db.QueryRow(`SELECT total_pages FROM books WHERE title = 'Dune'`).Scan(&totalPages); err != nil || totalPages != 412 {
+		t.Fatalf("expected total_pages kept, got %d (%v)", totalPages, err)
+	}
+	var target int
+	var owner int64
+	if err := db.QueryRow(`SELECT target, user_id FROM goals WHERE kind = 'books_per_year'`).Scan(&target, &owner); err != nil || target != 24 || owner != 1 {
+		t.Fatalf("expected the goal kept for the default user, got %d/%d (%v)", target, owner, err)
+	}
+	var n int
+	_ = db.QueryRow(`SELECT COUNT(*) FROM schema_migrations WHERE version IN (2, 3)`).Scan(&n)
+	if n != 2 {
+		t.Fatalf("0002 and 0003 should be recorded as applied, got %d", n)
+	}
+}
+
+func TestMigrate_RefusesNewerDatabase(t *testing.T) {
+	db, err := sql.Open("sqlite", "file::memory:")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+	if err := handlers.Migrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	if _, err := db.Exec(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (99999, 'future', '2100-01-01T00:00:00Z')`); err != nil {
+		t.Fatalf("insert future version: %v", err)
+	}
+
+	err = handlers.Migrate(db)
+	if !errors.Is(err, handlers.ErrSchemaTooNew) {
+		t.Fatalf("expected ErrSchemaTooNew, got %v", err)
+	}
+}
+
+func TestOpenStore_MigratesFileAndReopens(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reading.sqlite")
+
+	for i := 0; i < 2; i++ {
+		store, err := handlers.OpenStore(path)
+		if err != nil {
+			t.Fatalf("open #%d: %v", i+1, err)
+		}
+		if store.Dialect() != "sqlite" {
+			t.Fatalf("expected sqlite store, got %q", store.Dialect())
+		}
+		var fk int
+		_ = store.QueryRow(context.Background(), `PRAGMA foreign_keys`).Scan(&fk)
+		_ = store.Close()
+		if fk != 1 {
+			t.Fatalf("expected foreign_keys on, got %d", fk)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+-- PostgreSQL support starts at schema version 6: this creates everything the
+-- SQLite migrations 0001-0006 add up to. Later migrations keep the same
+-- number in both directories.
+--
+-- Timestamps are RFC3339 UTC text, as in SQLite, so both stores compare and
+-- return them the same way.
+
+CREATE TABLE users (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE,
+	created_at TEXT NOT NULL DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"')
+);
+
+INSERT INTO users (id, name) VALUES (1, 'default');
+SELECT setval(pg_get_serial_sequence('users', 'id'), 1);
+
+CREATE TABLE books (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES users(id),
+	title TEXT NOT NULL,
+	author TEXT,
+	source TEXT,
+	total_pages INTEGER CHECK (total_pages IS NULL OR total_pages > 0),
+	created_at TEXT NOT NULL DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"'),
+	UNIQUE (user_id, title)
+);
+
+CREATE TABLE sessions (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES users(id),
+	book_id BIGINT NOT NULL REFERENCES books(id),
+	device_id TEXT NOT NULL,
+	start_page INTEGER NOT NULL CHECK (start_page >= 0),
+	end_page INTEGER CHECK (end_page IS NULL OR end_page >= 0),
+	started_at TEXT NOT NULL, -- RFC3339 UTC
+	ended_at TEXT, -- RFC3339 UTC
+	duration_seconds INTEGER, -- set when stopping
+	created_at TEXT NOT NULL DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"')
+);
+
+CREATE INDEX idx_sessions_device_open
+	ON sessions(user_id, device_id)
+	WHERE ended_at IS NULL;
+
+CREATE INDEX idx_sessions_book_started
+	ON sessions(book_id, started_at DESC);
+
+CREATE INDEX idx_sessions_user_date
+	ON sessions(user_id, started_at);
+
+CREATE INDEX idx_sessions_user_ended
+	ON sessions(user_id, ended_at);
+
+CREATE INDEX idx_sessions_device_started
+	ON sessions(user_id, device_id, started_at DESC);
+
+CREATE TABLE goals (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES users(id),
+	kind TEXT NOT NULL CHECK (kind IN ('books_per_year', 'minutes_per_day', 'pages_per_week')),
+	target INTEGER NOT NULL CHECK (target > 0),
+	created_at TEXT NOT NULL DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"'),
+	UNIQUE (user_id, kind)
+);
+
+CREATE TABLE api_tokens (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES users(id),
+	name TEXT NOT NULL,
+	prefix TEXT NOT NULL,
+	token_hash TEXT NOT NULL UNIQUE,
+	scope TEXT NOT NULL CHECK (scope IN ('read', 'write')),
+	created_at TEXT NOT NULL DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"'),
+	last_used_at TEXT,
+	revoked_at TEXT,
+	device_id TEXT
+);
+
+CREATE INDEX idx_api_tokens_user
+	ON api_tokens(user_id);
+
+CREATE TABLE devices (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES users(id),
+	device_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	type TEXT NOT NULL CHECK (type IN ('iphone', 'ipad', 'mac', 'ereader', 'other')),
+	timezone TEXT NOT NULL DEFAULT 'UTC',
+	created_at TEXT NOT NULL DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"'),
+	UNIQUE (user_id, device_id)
+);
@@ -0,0 +1,9 @@
+-- Catalog details, filled in by library imports such as Goodreads. shelves
+-- is a comma-separated list; added_on and finished_on are YYYY-MM-DD dates,
+-- which is all the imports know.
+
+ALTER TABLE books ADD COLUMN isbn TEXT;
+ALTER TABLE books ADD COLUMN read_status TEXT CHECK (read_status IS NULL OR read_status IN ('to_read', 'reading', 'read'));
+ALTER TABLE books ADD COLUMN shelves TEXT;
+ALTER TABLE books ADD COLUMN added_on TEXT;
+ALTER TABLE books ADD COLUMN finished_on TEXT;
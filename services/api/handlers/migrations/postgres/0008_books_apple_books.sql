@@ -0,0 +1,11 @@
+-- Apple Books library details. asset_id is Apple Books' ZASSETID, which
+-- session starts can match on instead of the title; reading_progress is a
+-- percentage and last_opened_at RFC3339 UTC.
+
+ALTER TABLE books ADD COLUMN asset_id TEXT;
+ALTER TABLE books ADD COLUMN reading_progress DOUBLE PRECISION CHECK (reading_progress IS NULL OR (reading_progress >= 0 AND reading_progress <= 100));
+ALTER TABLE books ADD COLUMN last_opened_at TEXT;
+
+CREATE UNIQUE INDEX idx_books_user_asset
+	ON books(user_id, asset_id)
+	WHERE asset_id IS NOT NULL;
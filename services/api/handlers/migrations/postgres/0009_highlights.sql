@@ -0,0 +1,19 @@
+-- Highlights and notes imported from e-readers. page and the location
+-- range are whatever the device reported; clipped_at is RFC3339 UTC.
+
+CREATE TABLE highlights (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES users(id),
+	book_id BIGINT NOT NULL REFERENCES books(id),
+	kind TEXT NOT NULL CHECK (kind IN ('highlight', 'note')),
+	text TEXT NOT NULL,
+	page INTEGER,
+	location_start INTEGER,
+	location_end INTEGER,
+	clipped_at TEXT,
+	source TEXT NOT NULL,
+	created_at TEXT NOT NULL DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"')
+);
+
+CREATE INDEX idx_highlights_book
+	ON highlights(book_id, location_start, page);
@@ -0,0 +1,10 @@
+-- Ratings and reviews. rating is 0.5-5 stars in half-star steps;
+-- reviewed_at is RFC3339 UTC.
+
+ALTER TABLE books ADD COLUMN rating DOUBLE PRECISION CHECK (rating IS NULL OR (rating >= 0.5 AND rating <= 5 AND rating * 2 = ROUND(rating * 2)));
+ALTER TABLE books ADD COLUMN review TEXT;
+ALTER TABLE books ADD COLUMN reviewed_at TEXT;
+
+CREATE INDEX idx_books_user_rating
+	ON books(user_id, rating)
+	WHERE rating IS NOT NULL;
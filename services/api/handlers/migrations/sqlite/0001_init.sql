@@ -0,0 +1,35 @@
+-- Initial schema. Uses IF NOT EXISTS so databases created before migrations
+-- existed are adopted as-is.
+
+CREATE TABLE IF NOT EXISTS books (
+	id INTEGER PRIMARY KEY,
+	title TEXT NOT NULL UNIQUE,
+	author TEXT,
+	source TEXT,
+	created_at TEXT NOT NULL DEFAULT (datetime('now'))
+);
+
+CREATE TABLE IF NOT EXISTS sessions (
+	id INTEGER PRIMARY KEY,
+	book_id INTEGER NOT NULL REFERENCES books(id),
+	device_id TEXT NOT NULL,
+	start_page INTEGER NOT NULL CHECK (start_page >= 0),
+	end_page INTEGER CHECK (end_page IS NULL OR end_page >= 0),
+	started_at TEXT NOT NULL, -- RFC3339 UTC
+	ended_at TEXT, -- RFC3339 UTC
+	duration_seconds INTEGER, -- set when stopping
+	created_at TEXT NOT NULL DEFAULT (datetime('now'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_device_open
+	ON sessions(device_id)
+	WHERE ended_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_sessions_book_started
+	ON sessions(book_id, started_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_date
+	ON sessions(started_at);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_device_started
+	ON sessions(device_id, started_at DESC);
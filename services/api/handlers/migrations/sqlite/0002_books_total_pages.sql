@@ -0,0 +1,5 @@
+-- Binaries from before migrations added total_pages to the CREATE TABLE of
+-- new databases, so those already have it.
+-- adopt-if: SELECT COUNT(*) FROM pragma_table_info('books') WHERE name = 'total_pages'
+
+ALTER TABLE books ADD COLUMN total_pages INTEGER CHECK (total_pages IS NULL OR total_pages > 0);
@@ -0,0 +1,10 @@
+-- Binaries from before migrations created goals in new and existing
+-- databases alike, with this same definition.
+-- adopt-if: SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'goals'
+
+CREATE TABLE goals (
+	id INTEGER PRIMARY KEY,
+	kind TEXT NOT NULL UNIQUE CHECK (kind IN ('books_per_year', 'minutes_per_day', 'pages_per_week')),
+	target INTEGER NOT NULL CHECK (target > 0),
+	created_at TEXT NOT NULL DEFAULT (datetime('now'))
+);
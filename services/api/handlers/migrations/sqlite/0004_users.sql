@@ -0,0 +1,72 @@
+-- Accounts. Books, sessions and goals become per user; everything created
+-- before this migration belongs to the default user (id 1).
+
+CREATE TABLE users (
+	id INTEGER PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE,
+	created_at TEXT NOT NULL DEFAULT (datetime('now'))
+);
+
+INSERT INTO users (id, name, created_at)
+VALUES (1, 'default', strftime('%Y-%m-%dT%H:%M:%SZ', 'now'));
+
+CREATE TABLE books_new (
+	id INTEGER PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id),
+	title TEXT NOT NULL,
+	author TEXT,
+	source TEXT,
+	total_pages INTEGER CHECK (total_pages IS NULL OR total_pages > 0),
+	created_at TEXT NOT NULL DEFAULT (datetime('now')),
+	UNIQUE (user_id, title)
+);
+INSERT INTO books_new (id, user_id, title, author, source, total_pages, created_at)
+SELECT id, 1, title, author, source, total_pages, created_at FROM books;
+DROP TABLE books;
+ALTER TABLE books_new RENAME TO books;
+
+CREATE TABLE sessions_new (
+	id INTEGER PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id),
+	book_id INTEGER NOT NULL REFERENCES books(id),
+	device_id TEXT NOT NULL,
+	start_page INTEGER NOT NULL CHECK (start_page >= 0),
+	end_page INTEGER CHECK (end_page IS NULL OR end_page >= 0),
+	started_at TEXT NOT NULL, -- RFC3339 UTC
+	ended_at TEXT, -- RFC3339 UTC
+	duration_seconds INTEGER, -- set when stopping
+	created_at TEXT NOT NULL DEFAULT (datetime('now'))
+);
+INSERT INTO sessions_new (id, user_id, book_id, device_id, start_page, end_page, started_at, ended_at, duration_seconds, created_at)
+SELECT id, 1, book_id, device_id, start_page, end_page, started_at, ended_at, duration_seconds, created_at FROM sessions;
+DROP TABLE sessions;
+ALTER TABLE sessions_new RENAME TO sessions;
+
+CREATE INDEX idx_sessions_device_open
+	ON sessions(user_id, device_id)
+	WHERE ended_at IS NULL;
+
+CREATE INDEX idx_sessions_book_started
+	ON sessions(book_id, started_at DESC);
+
+CREATE INDEX idx_sessions_user_date
+	ON sessions(user_id, started_at);
+
+CREATE INDEX idx_sessions_user_ended
+	ON sessions(user_id, ended_at);
+
+CREATE INDEX idx_sessions_device_started
+	ON sessions(user_id, device_id, started_at DESC);
+
+CREATE TABLE goals_new (
+	id INTEGER PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id),
+	kind TEXT NOT NULL CHECK (kind IN ('books_per_year', 'minutes_per_day', 'pages_per_week')),
+	target INTEGER NOT NULL CHECK (target > 0),
+	created_at TEXT NOT NULL DEFAULT (datetime('now')),
+	UNIQUE (user_id, kind)
+);
+INSERT INTO goals_new (id, user_id, kind, target, created_at)
+SELECT id, 1, kind, target, created_at FROM goals;
+DROP TABLE goals;
+ALTER TABLE goals_new RENAME TO goals;
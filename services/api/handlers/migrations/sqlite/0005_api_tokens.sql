@@ -0,0 +1,17 @@
+-- Bearer tokens. Only the SHA-256 of a token is stored; prefix is kept so
+-- tokens can be told apart in listings.
+
+CREATE TABLE api_tokens (
+	id INTEGER PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id),
+	name TEXT NOT NULL,
+	prefix TEXT NOT NULL,
+	token_hash TEXT NOT NULL UNIQUE,
+	scope TEXT NOT NULL CHECK (scope IN ('read', 'write')),
+	created_at TEXT NOT NULL DEFAULT (datetime('now')),
+	last_used_at TEXT,
+	revoked_at TEXT
+);
+
+CREATE INDEX idx_api_tokens_user
+	ON api_tokens(user_id);
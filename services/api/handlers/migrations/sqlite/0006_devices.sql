@@ -0,0 +1,16 @@
+-- Registered devices. sessions.device_id stays free-form text; a device is
+-- registered when a row here has the same (user_id, device_id).
+
+CREATE TABLE devices (
+	id INTEGER PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id),
+	device_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	type TEXT NOT NULL CHECK (type IN ('iphone', 'ipad', 'mac', 'ereader', 'other')),
+	timezone TEXT NOT NULL DEFAULT 'UTC',
+	created_at TEXT NOT NULL DEFAULT (datetime('now')),
+	UNIQUE (user_id, device_id)
+);
+
+-- A token bound to a device stands in for device_id on session requests.
+ALTER TABLE api_tokens ADD COLUMN device_id TEXT;
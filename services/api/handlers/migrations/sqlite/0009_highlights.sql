@@ -0,0 +1,19 @@
+-- Highlights and notes imported from e-readers. page and the location
+-- range are whatever the device reported; clipped_at is RFC3339 UTC.
+
+CREATE TABLE highlights (
+	id INTEGER PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id),
+	book_id INTEGER NOT NULL REFERENCES books(id),
+	kind TEXT NOT NULL CHECK (kind IN ('highlight', 'note')),
+	text TEXT NOT NULL,
+	page INTEGER,
+	location_start INTEGER,
+	location_end INTEGER,
+	clipped_at TEXT,
+	source TEXT NOT NULL,
+	created_at TEXT NOT NULL DEFAULT (datetime('now'))
+);
+
+CREATE INDEX idx_highlights_book
+	ON highlights(book_id, location_start, page);
@@ -0,0 +1,33 @@
+-- Notes: highlights, quotes and thoughts on a book, optionally tied to the
+-- session and page they were written at. They take over the imported
+-- highlights, which keep their ids. tags is comma-separated.
+
+CREATE TABLE notes (
+	id INTEGER PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id),
+	book_id INTEGER NOT NULL REFERENCES books(id),
+	session_id INTEGER REFERENCES sessions(id) ON DELETE SET NULL,
+	kind TEXT NOT NULL CHECK (kind IN ('highlight', 'quote', 'note')),
+	text TEXT NOT NULL,
+	tags TEXT,
+	page INTEGER CHECK (page IS NULL OR page >= 0),
+	location_start INTEGER,
+	location_end INTEGER,
+	clipped_at TEXT,
+	source TEXT,
+	created_at TEXT NOT NULL DEFAULT (datetime('now')),
+	updated_at TEXT
+);
+
+INSERT INTO notes (id, user_id, book_id, kind, text, page, location_start, location_end, clipped_at, source, created_at)
+SELECT id, user_id, book_id, kind, text, page, location_start, location_end, clipped_at, source, created_at
+FROM highlights;
+
+DROP TABLE highlights;
+
+CREATE INDEX idx_notes_book
+	ON notes(book_id, location_start, page);
+
+CREATE INDEX idx_notes_session
+	ON notes(session_id)
+	WHERE session_id IS NOT NULL;
@@ -0,0 +1,352 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Note kinds, as kept in notes.kind.
+const (
+	noteKindHighlight = "highlight"
+	noteKindQuote     = "quote"
+	noteKindNote      = "note"
+)
+
+// noteRequest creates or replaces a note. It belongs to book_id or, when
+// only session_id is given, to that session's book.
+type noteRequest struct {
+	BookID    *int64   `json:"book_id,omitempty"`
+	SessionID *int64   `json:"session_id,omitempty"`
+	Kind      string   `json:"kind"` // default "note"
+	Text      string   `json:"text"`
+	Page      *int     `json:"page,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+}
+
+type noteItem struct {
+	ID            int64    `json:"id"`
+	BookID        int64    `json:"book_id"`
+	SessionID     *int64   `json:"session_id,omitempty"`
+	Kind          string   `json:"kind"`
+	Text          string   `json:"text"`
+	Tags          []string `json:"tags"`
+	Page          *int     `json:"page,omitempty"`
+	LocationStart *int     `json:"location_start,omitempty"`
+	LocationEnd   *int     `json:"location_end,omitempty"`
+	ClippedAt     *string  `json:"clipped_at,omitempty"`
+	Source        *string  `json:"source,omitempty"`
+	CreatedAt     string   `json:"created_at"`
+	UpdatedAt     *string  `json:"updated_at,omitempty"`
+}
+
+const noteColumns = `id, book_id, session_id, kind, text, tags, page, location_start, location_end, clipped_at, source, created_at, updated_at`
+
+// scanFields returns Scan destinations for noteColumns and a func to call
+// after Scan, which fills in Tags.
+func (it *noteItem) scanFields() ([]any, func()) {
+	var tags *string
+	return []any{&it.ID, &it.BookID, &it.SessionID, &it.Kind, &it.Text, &tags, &it.Page, &it.LocationStart, &it.LocationEnd, &it.ClippedAt, &it.Source, &it.CreatedAt, &it.UpdatedAt},
+		func() { it.Tags = append([]string{}, splitList(tags)...) }
+}
+
+func (req *noteRequest) validate() error {
+	req.Kind = strings.TrimSpace(req.Kind)
+	switch req.Kind {
+	case "":
+		req.Kind = noteKindNote
+	case noteKindHighlight, noteKindQuote, noteKindNote:
+	default:
+		return ErrInvalidValue.Field("kind", "kind must be one of highlight, quote, note")
+	}
+	req.Text = strings.TrimSpace(req.Text)
+	if req.Text == "" {
+		return ErrRequired.Field("text", "text is required")
+	}
+	if req.BookID == nil && req.SessionID == nil {
+		return ErrRequired.Field("book_id", "book_id or session_id is required")
+	}
+	if req.Page != nil && *req.Page < 0 {
+		return ErrInvalidPage.Field("page", "page must be >= 0")
+	}
+	tags := make([]string, 0, len(req.Tags))
+	for _, t := range req.Tags {
+		t = strings.TrimSpace(t)
+		if t == "" || strings.Contains(t, ",") {
+			return ErrInvalidValue.Field("tags", "tags must be non-empty and contain no commas")
+		}
+		if !slices.Contains(tags, t) {
+			tags = append(tags, t)
+		}
+	}
+	req.Tags = tags
+	return nil
+}
+
+// noteBook returns the book a note request belongs to, checking that its
+// book and session are the user's and agree with each other.
+func noteBook(ctx context.Context, tx Querier, uid int64, req noteRequest) (int64, error) {
+	if req.SessionID != nil {
+		var bookID int64
+		err := tx.QueryRow(ctx, `SELECT book_id FROM sessions WHERE id = ? AND user_id = ?`, *req.SessionID, uid).Scan(&bookID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrSessionNotFound
+		}
+		if err != nil {
+			return 0, err
+		}
+		if req.BookID != nil && *req.BookID != bookID {
+			return 0, ErrInvalidValue.Field("session_id", "session_id is a session of another book")
+		}
+		return bookID, nil
+	}
+	var exists int
+	err := tx.QueryRow(ctx, `SELECT 1 FROM books WHERE id = ? AND user_id = ?`, *req.BookID, uid).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrBookNotFound
+	}
+	return *req.BookID, err
+}
+
+func insertNote(ctx context.Context, tx Querier, uid, bookID int64, sessionID *int64, kind, text string, tags []string, page *int, createdAt string) (id int64, err error) {
+	ctx, span := startSpan(ctx, "insertNote")
+	defer func() { endSpan(span, err) }()
+	err = tx.QueryRow(ctx, `
+		INSERT INTO notes (user_id, book_id, session_id, kind, text, tags, page, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`, uid, bookID, sessionID, kind, text, joinList(tags), page, createdAt).Scan(&id)
+	return id, err
+}
+
+// insertImportedNote adds an archived note as it was, with its import
+// details and times.
+func insertImportedNote(ctx context.Context, tx Querier, uid, bookID int64, sessionID *int64, n archiveNote) (err error) {
+	ctx, span := startSpan(ctx, "insertImportedNote")
+	defer func() { endSpan(span, err) }()
+	_, err = tx.Exec(ctx, `
+		INSERT INTO notes (user_id, book_id, session_id, kind, text, tags, page, location_start, location_end, clipped_at, source, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, uid, bookID, sessionID, n.Kind, n.Text, joinList(n.Tags), n.Page, n.LocationStart, n.LocationEnd, n.ClippedAt, n.Source, n.CreatedAt, n.UpdatedAt)
+	return err
+}
+
+func findNote(ctx context.Context, q Querier, userID, id int64) (noteItem, error) {
+	var it noteItem
+	fields, scanned := it.scanFields()
+	err := q.QueryRow(ctx, `SELECT `+noteColumns+` FROM notes WHERE id = ? AND user_id = ?`, id, userID).Scan(fields...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return it, ErrNoteNotFound
+	}
+	scanned()
+	return it, err
+}
+
+func (a *App) createNote(w http.ResponseWriter, r *http.Request) {
+	var req noteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, ErrInvalidJSON)
+		return
+	}
+	if err := req.validate(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	uid := userID(ctx)
+	var out noteItem
+	err := a.Store.WithTx(ctx, func(ctx context.Context, tx Querier) error {
+		bookID, err := noteBook(ctx, tx, uid, req)
+		if err != nil {
+			return err
+		}
+		id, err := insertNote(ctx, tx, uid, bookID, req.SessionID, req.Kind, req.Text, req.Tags, req.Page, timeOrNowRFC3339(nil))
+		if err != nil {
+			return err
+		}
+		out, err = findNote(ctx, tx, uid, id)
+		return err
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, out)
+}
+
+func (a *App) getNote(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	it, err := findNote(r.Context(), a.Store, userID(r.Context()), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, it)
+}
+
+// updateNote replaces what a note says and where it belongs. Where an
+// import found it (location, clipped_at, source) stays.
+func (a *App) updateNote(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	var req noteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, ErrInvalidJSON)
+		return
+	}
+	if err := req.validate(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	uid := userID(ctx)
+	var out noteItem
+	err = a.Store.WithTx(ctx, func(ctx context.Context, tx Querier) error {
+		if _, err := findNote(ctx, tx, uid, id); err != nil {
+			return err
+		}
+		bookID, err := noteBook(ctx, tx, uid, req)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, `
+			UPDATE notes
+			SET book_id = ?, session_id = ?, kind = ?, text = ?, tags = ?, page = ?, updated_at = ?
+			WHERE id = ?
+		`, bookID, req.SessionID, req.Kind, req.Text, joinList(req.Tags), req.Page, timeOrNowRFC3339(nil), id); err != nil {
+			return err
+		}
+		out, err = findNote(ctx, tx, uid, id)
+		return err
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (a *App) deleteNote(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	res, err := a.Store.Exec(r.Context(), `DELETE FROM notes WHERE id = ? AND user_id = ?`, id, userID(r.Context()))
+	if err != nil {
+		serverError(w, r, err, "internal error")
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		writeError(w, r, ErrNoteNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// listBookNotes returns a book's notes in reading order: by location, then
+// page, then as written. ?kind= and ?tag= narrow them down.
+func (a *App) listBookNotes(w http.ResponseWriter, r *http.Request) {
+	a.listNotes(w, r, false)
+}
+
+// listBookHighlights is the route from before notes existed. It still lists
+// only what Kindle imports brought in, highlights and notes, as it used to.
+func (a *App) listBookHighlights(w http.ResponseWriter, r *http.Request) {
+	a.listNotes(w, r, true)
+}
+
+func (a *App) listNotes(w http.ResponseWriter, r *http.Request, kindleOnly bool) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	q := r.URL.Query()
+	kind := strings.TrimSpace(q.Get("kind"))
+	switch {
+	case kindleOnly && kind != "" && kind != noteKindHighlight && kind != noteKindNote:
+		writeError(w, r, ErrInvalidValue.Field("kind", "kind must be highlight or note"))
+		return
+	case kind != "" && kind != noteKindHighlight && kind != noteKindQuote && kind != noteKindNote:
+		writeError(w, r, ErrInvalidValue.Field("kind", "kind must be one of highlight, quote, note"))
+		return
+	}
+	tag := strings.TrimSpace(q.Get("tag"))
+
+	ctx := r.Context()
+	var exists int
+	err = a.Store.QueryRow(ctx, `SELECT 1 FROM books WHERE id = ? AND user_id = ?`, id, userID(ctx)).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, r, ErrBookNotFound)
+		return
+	}
+	if err != nil {
+		serverError(w, r, err, "query failed")
+		return
+	}
+
+	where := "WHERE book_id = ?"
+	args := []any{id}
+	if kindleOnly {
+		where += " AND source = 'kindle'"
+	}
+	if kind != "" {
+		where += " AND kind = ?"
+		args = append(args, kind)
+	}
+	if tag != "" {
+		where += ` AND ',' || tags || ',' LIKE ? ESCAPE '\'`
+		args = append(args, "%,"+escapeLike(tag)+",%")
+	}
+	rows, err := a.Store.Query(ctx, `
+SELECT `+noteColumns+`
+FROM notes
+`+where+`
+ORDER BY location_start IS NULL, location_start, page IS NULL, page, id;`, args...)
+	if err != nil {
+		serverError(w, r, err, "query failed")
+		return
+	}
+	defer rows.Close()
+
+	items := make([]noteItem, 0)
+	for rows.Next() {
+		var it noteItem
+		fields, scanned := it.scanFields()
+		if err := rows.Scan(fields...); err != nil {
+			serverError(w, r, err, "scan failed")
+			return
+		}
+		scanned()
+		items = append(items, it)
+	}
+	if err := rows.Err(); err != nil {
+		serverError(w, r, err, "row error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"items": items,
+		"meta":  map[string]any{"count": len(items), "book_id": id},
+	})
+}
@@ -0,0 +1,162 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type noteResponse struct {
+	ID        int64    `json:"id"`
+	BookID    int64    `json:"book_id"`
+	SessionID *int64   `json:"session_id"`
+	Kind      string   `json:"kind"`
+	Text      string   `json:"text"`
+	Tags      []string `json:"tags"`
+	Page      *int     `json:"page"`
+	UpdatedAt *string  `json:"updated_at"`
+}
+
+func TestNotes_CRUD(t *testing.T) {
+	r := newTestServer(t)
+	readSession(t, r, "ipad", "Dune", 0, 20, "2025-09-01T10:00:00Z", "2025-09-01T10:30:00Z")
+
+	// A note on the session lands on the session's book.
+	w := doJSON(t, r, http.MethodPost, "/v1/notes", map[string]any{
+		"session_id": 1, "kind": "quote", "text": " The spice must flow. ", "page": 12, "tags": []string{"spice", " arrakis ", "spice"},
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create expected 201, got %d body=%s", w.Code, w.Body.String())
+	}
+	var n noteResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &n)
+	if n.BookID != 1 || n.SessionID == nil || *n.SessionID != 1 || n.Kind != "quote" || n.Text != "The spice must flow." ||
+		strings.Join(n.Tags, "|") != "spice|arrakis" || n.Page == nil || *n.Page != 12 {
+		t.Fatalf("unexpected note: %s", w.Body.String())
+	}
+
+	w = doJSON(t, r, http.MethodPost, "/v1/notes", map[string]any{"book_id": 1, "text": "Paul is not a hero.", "page": 3})
+	if w.Code != http.StatusCreated || !strings.Contains(w.Body.String(), `"kind":"note"`) || !strings.Contains(w.Body.String(), `"tags":[]`) {
+		t.Fatalf("expected a plain note, got %d body=%s", w.Code, w.Body.String())
+	}
+
+	w = doJSON(t, r, http.MethodPut, "/v1/notes/1", map[string]any{"book_id": 1, "kind": "highlight", "text": "The spice must flow.", "tags": []string{"arrakis"}})
+	var updated noteResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &updated)
+	if w.Code != http.StatusOK || updated.Kind != "highlight" || updated.SessionID != nil || updated.Page != nil || updated.UpdatedAt == nil {
+		t.Fatalf("update should replace the note, got %d body=%s", w.Code, w.Body.String())
+	}
+
+	w = doJSON(t, r, http.MethodGet, "/v1/books/1/notes", nil)
+	var list struct {
+		Items []noteResponse `json:"items"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &list)
+	if len(list.Items) != 2 || list.Items[0].ID != 2 || list.Items[1].ID != 1 {
+		t.Fatalf("expected the page 3 note before the unpaged one, got %s", w.Body.String())
+	}
+	w = doJSON(t, r, http.MethodGet, "/v1/books/1/notes?tag=arrakis&kind=highlight", nil)
+	_ = json.Unmarshal(w.Body.Bytes(), &list)
+	if len(list.Items) != 1 || list.Items[0].ID != 1 {
+		t.Fatalf("tag filter: %s", w.Body.String())
+	}
+
+	if w := doJSON(t, r, http.MethodDelete, "/v1/notes/1", nil); w.Code != http.StatusNoContent {
+		t.Fatalf("delete expected 204, got %d", w.Code)
+	}
+	if w := doJSON(t, r, http.MethodGet, "/v1/notes/1", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("deleted note expected 404, got %d", w.Code)
+	}
+}
+
+func TestNotes_Validation(t *testing.T) {
+	r := newTestServer(t)
+	readSession(t, r, "ipad", "Dune", 0, 20, "2025-09-01T10:00:00Z", "2025-09-01T10:30:00Z")
+	readSession(t, r, "ipad", "Emma", 0, 20, "2025-09-02T10:00:00Z", "2025-09-02T10:30:00Z")
+
+	cases := []struct {
+		name   string
+		body   map[string]any
+		status int
+		want   string
+	}{
+		{"no text", map[string]any{"book_id": 1, "text": "  "}, http.StatusBadRequest, "text"},
+		{"no book", map[string]any{"text": "x"}, http.StatusBadRequest, "book_id"},
+		{"bad kind", map[string]any{"book_id": 1, "text": "x", "kind": "bookmark"}, http.StatusBadRequest, "kind"},
+		{"bad tag", map[string]any{"book_id": 1, "text": "x", "tags": []string{"a,b"}}, http.StatusBadRequest, "tags"},
+		{"unknown book", map[string]any{"book_id": 9, "text": "x"}, http.StatusNotFound, "book_not_found"},
+		{"unknown session", map[string]any{"session_id": 9, "text": "x"}, http.StatusNotFound, "session_not_found"},
+		{"other book's session", map[string]any{"book_id": 1, "session_id": 2, "text": "x"}, http.StatusBadRequest, "session_id"},
+	}
+	for _, tc := range cases {
+		w := doJSON(t, r, http.MethodPost, "/v1/notes", tc.body)
+		if w.Code != tc.status || !strings.Contains(w.Body.String(), tc.want) {
+			t.Errorf("%s: expected %d mentioning %q, got %d body=%s", tc.name, tc.status, tc.want, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestNotes_TagFilterMatchesLiterally(t *testing.T) {
+	r := newTestServer(t)
+	readSession(t, r, "ipad", "Dune", 0, 20, "2025-09-01T10:00:00Z", "2025-09-01T10:30:00Z")
+	for _, tags := range [][]string{{"100%"}, {"a_b"}, {"axb"}, {`c\d`}} {
+		if w := doJSON(t, r, http.MethodPost, "/v1/notes", map[string]any{"book_id": 1, "text": "x", "tags": tags}); w.Code != http.StatusCreated {
+			t.Fatalf("create expected 201, got %d body=%s", w.Code, w.Body.String())
+		}
+	}
+
+	cases := []struct {
+		tag  string
+		want []int64
+	}{
+		{"%", nil},
+		{"_", nil},
+		{"100%", []int64{1}},
+		{"a_b", []int64{2}},
+		{`c\d`, []int64{4}},
+		{`\`, nil},
+	}
+	for _, tc := range cases {
+		w := doJSON(t, r, http.MethodGet, "/v1/books/1/notes?tag="+url.QueryEscape(tc.tag), nil)
+		var list struct {
+			Items []noteResponse `json:"items"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &list)
+		var got []int64
+		for _, it := range list.Items {
+			got = append(got, it.ID)
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("tag=%s: expected notes %v, got %v", tc.tag, tc.want, got)
+		}
+	}
+}
+
+func TestSessionStop_KeepsNote(t *testing.T) {
+	r := newTestServer(t)
+	doJSON(t, r, http.MethodPost, "/v1/session/start", map[string]any{"device_id": "iphone", "book_title": "Dune", "start_page": 10})
+
+	w := doJSON(t, r, http.MethodPost, "/v1/session/stop", map[string]any{"device_id": "iphone", "end_page": 42, "note": "Jessica knew all along.\n"})
+	var out struct {
+		ID   int64        `json:"id"`
+		Note noteResponse `json:"note"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &out)
+	if w.Code != http.StatusOK || out.Note.Text != "Jessica knew all along." || out.Note.Kind != "note" ||
+		out.Note.SessionID == nil || *out.Note.SessionID != out.ID || out.Note.Page == nil || *out.Note.Page != 42 {
+		t.Fatalf("stop should keep the note on the session, got %d body=%s", w.Code, w.Body.String())
+	}
+
+	// A blank note is no note.
+	doJSON(t, r, http.MethodPost, "/v1/session/start", map[string]any{"device_id": "iphone", "book_title": "Dune", "start_page": 42})
+	w = doJSON(t, r, http.MethodPost, "/v1/session/stop", map[string]any{"device_id": "iphone", "end_page": 50, "note": " "})
+	if strings.Contains(w.Body.String(), `"note"`) {
+		t.Fatalf("blank note should be ignored: %s", w.Body.String())
+	}
+	if w := doJSON(t, r, http.MethodGet, "/v1/books/1/notes", nil); !strings.Contains(w.Body.String(), `"count":1`) {
+		t.Fatalf("expected one note on Dune: %s", w.Body.String())
+	}
+}
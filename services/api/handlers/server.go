@@ -1,42 +1,128 @@
 package handlers
 
 import (
-	"database/sql"
+	"log/slog"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type App struct {
-	DB *sql.DB
+	Store Store
+
+	// AppName and AppVersion are reported by /v1/version; empty means the
+	// built-in defaults.
+	AppName    string
+	AppVersion string
+
+	// BackupDir receives scheduled backups and the snapshot taken before
+	// each restore; empty means restores keep no copy of what they replace.
+	BackupDir string
+
+	// StrictDevices rejects session requests for unregistered device_ids.
+	StrictDevices bool
+
+	// Logger receives one line per request; nil means slog.Default().
+	Logger *slog.Logger
+
+	// TracerProvider receives a span per request and per repository and
+	// database call; nil means the global otel provider.
+	TracerProvider trace.TracerProvider
+
+	metrics *metrics
+}
+
+// NewServer returns the API routes for store with default settings.
+func NewServer(store Store) http.Handler {
+	return (&App{Store: store}).Routes()
 }
 
-func NewServer(db *sql.DB) http.Handler {
-	app := &App{DB: db}
+// Routes returns the API router for the app. It also sets up the app's
+// metrics, wrapping Store so database calls are timed and traced.
+func (app *App) Routes() http.Handler {
+	if app.metrics == nil {
+		app.metrics = newMetrics(app.Store)
+		app.Store = app.metrics.instrument(traceStore(app.Store))
+	}
 
 	r := chi.NewRouter()
 	r.Use(corsMW)
 	r.Use(middleware.RequestID)
-	r.Use(middleware.Logger)
-	r.Use(middleware.Recoverer)
+	r.Use(app.requestLogger)
+	r.Use(app.tracing)
+	r.Use(app.metrics.middleware)
+	r.Use(app.recoverer)
+
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) { writeError(w, r, ErrNotFound) })
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) { writeError(w, r, ErrMethodNotAllowed) })
+
+	r.Get("/metrics", app.metrics.handler().ServeHTTP)
 
 	r.Route("/v1", func(v chi.Router) {
 		v.Get("/health", app.health)
-		v.Get("/version", app.version)
 
-		v.Post("/session/start", app.startSession)
-		v.Post("/session/stop", app.stopSession)
-		v.Post("/session/continue", app.continueSession)
-		v.Get("/sessions/open", app.openSession)
+		v.Group(func(u chi.Router) {
+			u.Use(app.authenticate)
+
+			u.Get("/version", app.version)
+
+			u.Post("/tokens", app.createToken)
+			u.Get("/tokens", app.listTokens)
+			u.Delete("/tokens/{id}", app.revokeToken)
+
+			u.Post("/users", app.createUser)
+			u.Get("/users", app.listUsers)
+			u.Get("/users/me", app.currentUser)
+
+			u.Post("/session/start", app.startSession)
+			u.Post("/session/stop", app.stopSession)
+			u.Post("/session/continue", app.continueSession)
+			u.Get("/sessions/open", app.openSession)
+
+			u.Get("/books", app.listBooks)
+			u.Get("/books/recent", app.recentBooks)
+			u.Get("/books/reading/forecast", app.readingForecast)
+			u.Get("/books/{id}", app.getBook)
+			u.Get("/books/{id}/notes", app.listBookNotes)
+			u.Get("/books/{id}/highlights", app.listBookHighlights)
+			u.Put("/books/{id}/review", app.putReview)
+
+			u.Get("/stats/weekly", app.statsWeekly)
+			u.Get("/stats/speed", app.statsSpeed)
+			u.Get("/stats/heatmap", app.statsHeatmap)
+			u.Get("/stats/year/{yyyy}", app.statsYear)
+			u.Get("/stats/ratings", app.statsRatings)
+
+			u.Get("/sessions", app.listSessions)
+
+			u.Get("/export/sessions.csv", app.exportSessionsCSV)
+			u.Get("/export/sessions.ndjson", app.exportSessionsNDJSON)
+			u.Get("/export/books.csv", app.exportBooksCSV)
+			u.Get("/export/archive", app.exportArchive)
+			u.Post("/import/archive", app.importArchive)
+			u.Post("/import/goodreads", app.importGoodreads)
+			u.Post("/import/kindle", app.importKindle)
 
-		v.Get("/books", app.listBooks)
-		v.Get("/books/recent", app.recentBooks)
+			u.Post("/admin/backup", app.backup)
+			u.Post("/admin/restore", app.restore)
 
-		v.Get("/stats/weekly", app.statsWeekly)
+			u.Post("/devices", app.registerDevice)
+			u.Get("/devices", app.listDevices)
 
-		v.Get("/sessions", app.listSessions)
+			u.Post("/goals", app.createGoal)
+			u.Get("/goals", app.listGoals)
+			u.Get("/goals/{id}", app.getGoal)
+			u.Put("/goals/{id}", app.updateGoal)
+			u.Delete("/goals/{id}", app.deleteGoal)
+			u.Get("/goals/{id}/progress", app.getGoalProgress)
 
+			u.Post("/notes", app.createNote)
+			u.Get("/notes/{id}", app.getNote)
+			u.Put("/notes/{id}", app.updateNote)
+			u.Delete("/notes/{id}", app.deleteNote)
+		})
 	})
 
 	return r
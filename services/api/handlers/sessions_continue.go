@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
@@ -12,20 +13,20 @@ import (
 func (a *App) continueSession(w http.ResponseWriter, r *http.Request) {
 	var req continueSessionRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeErr(w, http.StatusBadRequest, "invalid JSON body")
+		writeError(w, r, ErrInvalidJSON)
 		return
 	}
-	req.DeviceID = strings.TrimSpace(req.DeviceID)
-	if req.DeviceID == "" {
-		writeErr(w, http.StatusBadRequest, "device_id is required")
+	deviceID, ok := a.resolveDevice(w, r, req.DeviceID)
+	if !ok {
 		return
 	}
+	req.DeviceID = deviceID
 
 	var startedAt string
 	if req.StartedAt != nil && strings.TrimSpace(*req.StartedAt) != "" {
 		t, err := parseRFC3339UTC(*req.StartedAt)
 		if err != nil {
-			writeErr(w, http.StatusBadRequest, "started_at must be RFC3339 (e.g., 2025-09-16T23:25:00Z)")
+			writeError(w, r, ErrInvalidTime.Field("started_at", "started_at must be RFC3339 (e.g., 2025-09-16T23:25:00Z)"))
 			return
 		}
 		startedAt = t.Format(time.RFC3339)
@@ -33,12 +34,17 @@ func (a *App) continueSession(w http.ResponseWriter, r *http.Request) {
 		startedAt = timeOrNowRFC3339(nil)
 	}
 
+	uid := userID(r.Context())
 	var out sessionResponse
+	// reopened is set when the device already has an open session, which is
+	// returned as is instead of starting another.
+	var reopened bool
 
-	err := withTx(a.DB, func(tx *sql.Tx) error {
-		openID, openBookID, openStart, openStarted, openCreated, err := openSessionByDevice(tx, req.DeviceID)
+	err := a.Store.WithTx(r.Context(), func(ctx context.Context, tx Querier) error {
+		reopened = false
+		openID, openBookID, openStart, openStarted, openCreated, err := openSessionByDevice(ctx, tx, uid, req.DeviceID)
 		if err == nil {
-			title, author, source, err := getBookInfo(tx, openBookID)
+			title, author, source, err := getBookInfo(ctx, tx, openBookID)
 			if err != nil {
 				return err
 			}
@@ -53,17 +59,16 @@ func (a *App) continueSession(w http.ResponseWriter, r *http.Request) {
 				Author:    author,
 				Source:    source,
 			}
-			writeJSON(w, http.StatusOK, out)
-			return errors.New("returned-open")
+			reopened = true
+			return nil
 		} else if !errors.Is(err, sql.ErrNoRows) {
 			return err
 		}
 
 		_, lastBookID, lastStartPage, lastEndPage, _, _, err :=
-			mostRecentSessionByDevice(tx, req.DeviceID)
+			mostRecentSessionByDevice(ctx, tx, uid, req.DeviceID)
 		if errors.Is(err, sql.ErrNoRows) {
-			writeErr(w, http.StatusNotFound, "no prior session to continue")
-			return errors.New("notfound")
+			return ErrNoPriorSession
 		} else if err != nil {
 			return err
 		}
@@ -74,12 +79,12 @@ func (a *App) continueSession(w http.ResponseWriter, r *http.Request) {
 		}
 
 		now := timeOrNowRFC3339(nil)
-		newID, err := insertSession(tx, lastBookID, req.DeviceID, startPage, startedAt, now)
+		newID, err := insertSession(ctx, tx, uid, lastBookID, req.DeviceID, startPage, startedAt, now)
 		if err != nil {
 			return err
 		}
 
-		title, author, source, err := getBookInfo(tx, lastBookID)
+		title, author, source, err := getBookInfo(ctx, tx, lastBookID)
 		if err != nil {
 			return err
 		}
@@ -99,16 +104,14 @@ func (a *App) continueSession(w http.ResponseWriter, r *http.Request) {
 	})
 
 	if err != nil {
-		switch err.Error() {
-		case "returned-open":
-			return
-		case "notfound":
-			return
-		default:
-			writeErr(w, http.StatusInternalServerError, "internal error")
-			return
-		}
+		writeError(w, r, err)
+		return
+	}
+	if reopened {
+		writeJSON(w, http.StatusOK, out)
+		return
 	}
 
+	a.metrics.sessionsStarted.WithLabelValues("continue").Inc()
 	writeJSON(w, http.StatusCreated, out)
 }
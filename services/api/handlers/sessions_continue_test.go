@@ -6,13 +6,11 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
-
-	"github.com/mk-slmn/booksmart/services/api/handlers"
 )
 
 func TestSessionContinue_ReturnsOpenIfExists(t *testing.T) {
 	db := newTestDB(t)
-	r := handlers.NewServer(db)
+	r := newTestRouter(db)
 
 	start := map[string]any{
 		"device_id":  "phone",
@@ -41,7 +39,7 @@ func TestSessionContinue_ReturnsOpenIfExists(t *testing.T) {
 
 func TestSessionContinue_CreatesNewFromLastClosed(t *testing.T) {
 	db := newTestDB(t)
-	r := handlers.NewServer(db)
+	r := newTestRouter(db)
 
 	start := map[string]any{
 		"device_id":  "laptop",
@@ -91,7 +89,7 @@ func TestSessionContinue_CreatesNewFromLastClosed(t *testing.T) {
 
 func TestSessionContinue_404WhenNoHistory(t *testing.T) {
 	db := newTestDB(t)
-	r := handlers.NewServer(db)
+	r := newTestRouter(db)
 
 	cont := map[string]any{"device_id": "unknown"}
 	bc, _ := json.Marshal(cont)
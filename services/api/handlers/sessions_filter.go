@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sessionFilter is the set of query parameters that narrow a session
+// listing. GET /v1/sessions and the session exports share it.
+type sessionFilter struct {
+	DeviceID  string
+	BookID    int64
+	BookTitle string
+	Author    string
+	Status    string // "open", "closed" or "" for both
+
+	// From and To bound DateField (started_at or ended_at) as a half-open
+	// range of RFC3339 UTC strings; either may be empty.
+	DateField string
+	From, To  string
+
+	MinDuration, MaxDuration *int64
+	HasEndPage               *bool
+}
+
+// parseSessionFilter reads:
+//
+//	device_id, book_id, book_title   exact matches
+//	author                           case-insensitive substring
+//	status                           open or closed
+//	from, to, date                   range on started_at (default) or ended_at;
+//	                                 YYYY-MM-DD dates are days in tz (default UTC)
+//	min_duration, max_duration       seconds, closed sessions only
+//	has_end_page                     true or false
+func parseSessionFilter(q url.Values) (sessionFilter, error) {
+	f := sessionFilter{
+		DeviceID:  strings.TrimSpace(q.Get("device_id")),
+		BookTitle: strings.TrimSpace(q.Get("book_title")),
+		Author:    strings.TrimSpace(q.Get("author")),
+		Status:    strings.ToLower(strings.TrimSpace(q.Get("status"))),
+		DateField: strings.ToLower(strings.TrimSpace(q.Get("date"))),
+	}
+
+	if v := strings.TrimSpace(q.Get("book_id")); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			return f, ErrInvalidValue.Field("book_id", "book_id must be a positive integer")
+		}
+		f.BookID = id
+	}
+	switch f.Status {
+	case "", "open", "closed":
+	default:
+		return f, ErrInvalidValue.Field("status", "status must be open or closed")
+	}
+
+	switch f.DateField {
+	case "":
+		f.DateField = "started_at"
+	case "started_at", "ended_at":
+	default:
+		return f, ErrInvalidValue.Field("date", "date must be started_at or ended_at")
+	}
+	loc := time.UTC
+	if v := strings.TrimSpace(q.Get("tz")); v != "" {
+		l, err := time.LoadLocation(v)
+		if err != nil {
+			return f, ErrInvalidTimeZone.Field("tz", "tz must be an IANA time zone (e.g., Europe/Berlin)")
+		}
+		loc = l
+	}
+	var from, to time.Time
+	if v := strings.TrimSpace(q.Get("from")); v != "" {
+		t, err := parseDateOrRFC3339(v, loc, false)
+		if err != nil {
+			return f, ErrInvalidTime.Field("from", "from must be YYYY-MM-DD or RFC3339")
+		}
+		from, f.From = t, t.UTC().Format(time.RFC3339)
+	}
+	if v := strings.TrimSpace(q.Get("to")); v != "" {
+		t, err := parseDateOrRFC3339(v, loc, true)
+		if err != nil {
+			return f, ErrInvalidTime.Field("to", "to must be YYYY-MM-DD or RFC3339")
+		}
+		to, f.To = t, t.UTC().Format(time.RFC3339)
+	}
+	if f.From != "" && f.To != "" && !from.Before(to) {
+		return f, ErrInvalidRange
+	}
+
+	for _, d := range []struct {
+		name string
+		dst  **int64
+	}{{"min_duration", &f.MinDuration}, {"max_duration", &f.MaxDuration}} {
+		if v := strings.TrimSpace(q.Get(d.name)); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || n < 0 {
+				return f, ErrInvalidValue.Field(d.name, d.name+" must be a whole number of seconds >= 0")
+			}
+			*d.dst = &n
+		}
+	}
+	if v := strings.TrimSpace(q.Get("has_end_page")); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return f, ErrInvalidValue.Field("has_end_page", "has_end_page must be true or false")
+		}
+		f.HasEndPage = &b
+	}
+	return f, nil
+}
+
+// where returns the conditions selecting userID's sessions that match f, for
+// a query over sessions s JOIN books b.
+func (f sessionFilter) where(userID int64) ([]string, []any) {
+	conds := []string{"s.user_id = ?"}
+	args := []any{userID}
+	add := func(cond string, a ...any) {
+		conds = append(conds, cond)
+		args = append(args, a...)
+	}
+
+	if f.DeviceID != "" {
+		add("s.device_id = ?", f.DeviceID)
+	}
+	if f.BookID != 0 {
+		add("s.book_id = ?", f.BookID)
+	}
+	if f.BookTitle != "" {
+		add("b.title = ?", f.BookTitle)
+	}
+	if f.Author != "" {
+		add(`LOWER(COALESCE(b.author,'')) LIKE ? ESCAPE '\'`, "%"+escapeLike(strings.ToLower(f.Author))+"%")
+	}
+	switch f.Status {
+	case "open":
+		add("s.ended_at IS NULL")
+	case "closed":
+		add("s.ended_at IS NOT NULL")
+	}
+	// DateField is one of two known column names, never user text.
+	if f.From != "" {
+		add("s."+f.DateField+" >= ?", f.From)
+	}
+	if f.To != "" {
+		add("s."+f.DateField+" < ?", f.To)
+	}
+	if f.MinDuration != nil {
+		add("s.duration_seconds >= ?", *f.MinDuration)
+	}
+	if f.MaxDuration != nil {
+		add("s.duration_seconds <= ?", *f.MaxDuration)
+	}
+	if f.HasEndPage != nil {
+		if *f.HasEndPage {
+			add("s.end_page IS NOT NULL")
+		} else {
+			add("s.end_page IS NULL")
+		}
+	}
+	return conds, args
+}
+
+// sessionSort is an ordering for session listings. expr is never NULL, so
+// it can key a cursor; sessions without a duration or end page sort as -1.
+type sessionSort struct {
+	name    string
+	expr    string
+	numeric bool
+	desc    bool
+}
+
+var sessionSorts = map[string]sessionSort{
+	"last_activity": {name: "last_activity", expr: "COALESCE(s.ended_at, s.started_at)"},
+	"started_at":    {name: "started_at", expr: "s.started_at"},
+	"duration":      {name: "duration", expr: "COALESCE(s.duration_seconds, -1)", numeric: true},
+	"pages":         {name: "pages", expr: "COALESCE(s.end_page - s.start_page, -1)", numeric: true},
+}
+
+// parseSessionSort reads sort= (last_activity, started_at, duration or
+// pages) and order= (asc or desc, default desc).
+func parseSessionSort(q url.Values) (sessionSort, error) {
+	name := strings.ToLower(strings.TrimSpace(q.Get("sort")))
+	if name == "" {
+		name = "last_activity"
+	}
+	s, ok := sessionSorts[name]
+	if !ok {
+		return s, ErrInvalidValue.Field("sort", "sort must be one of last_activity, started_at, duration, pages")
+	}
+	switch strings.ToLower(strings.TrimSpace(q.Get("order"))) {
+	case "", "desc":
+		s.desc = true
+	case "asc":
+	default:
+		return s, ErrInvalidValue.Field("order", "order must be asc or desc")
+	}
+	return s, nil
+}
+
+func (s sessionSort) order() string {
+	if s.desc {
+		return "desc"
+	}
+	return "asc"
+}
+
+// key identifies the ordering in cursors, so a cursor is only accepted
+// with the sort it was made for.
+func (s sessionSort) key() string {
+	return s.name + ":" + s.order()
+}
+
+// orderBy is the ORDER BY clause, with the id breaking ties.
+func (s sessionSort) orderBy() string {
+	dir := " " + strings.ToUpper(s.order())
+	return "ORDER BY " + s.expr + dir + ", s.id" + dir
+}
+
+// after is the condition selecting rows past c in this order.
+func (s sessionSort) after(c cursor) (string, []any, error) {
+	var key any = c.Key
+	if s.numeric {
+		n, err := strconv.ParseInt(c.Key, 10, 64)
+		if err != nil {
+			return "", nil, errInvalidCursor
+		}
+		key = n
+	}
+	op := ">"
+	if s.desc {
+		op = "<"
+	}
+	return "(" + s.expr + " " + op + " ? OR (" + s.expr + " = ? AND s.id " + op + " ?))", []any{key, key, c.ID}, nil
+}
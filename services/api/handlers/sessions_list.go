@@ -1,8 +1,8 @@
 package handlers
 
 import (
+	"context"
 	"net/http"
-	"strconv"
 	"strings"
 )
 
@@ -21,146 +21,143 @@ type sessionListItem struct {
 	CreatedAt       string  `json:"created_at"`
 	Status          string  `json:"status"`
 	LastActivity    string  `json:"last_activity"`
+
+	sortKey string // the listing's sort value, for the next cursor
 }
 
+// listSessions pages through the sessions matching sessionFilter, ordered by
+// sessionSort (newest activity first by default). Pages are keyed on the
+// sort value and id, so rows added while a client scrolls do not shift
+// later pages.
 func (a *App) listSessions(w http.ResponseWriter, r *http.Request) {
-	// pagination
-	limit := 20
-	if v := r.URL.Query().Get("limit"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil && n > 0 {
-			if n > 100 {
-				n = 100
-			}
-			limit = n
-		}
-	}
-	offset := 0
-	if v := r.URL.Query().Get("offset"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
-			offset = n
-		}
+	p, err := parsePage(r, 20, 100)
+	if err != nil {
+		writeError(w, r, err)
+		return
 	}
-
-	device := strings.TrimSpace(r.URL.Query().Get("device_id"))
-	bookTitle := strings.TrimSpace(r.URL.Query().Get("book_title"))
-
-	conds := make([]string, 0, 2)
-	args := make([]any, 0, 2)
-
-	if device != "" {
-		conds = append(conds, "s.device_id = ?")
-		args = append(args, device)
+	f, err := parseSessionFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, r, err)
+		return
 	}
-	if bookTitle != "" {
-		conds = append(conds, "b.title = ?")
-		args = append(args, bookTitle)
+	order, err := parseSessionSort(r.URL.Query())
+	if err != nil {
+		writeError(w, r, err)
+		return
 	}
 
-	where := ""
-	if len(conds) > 0 {
-		where = "WHERE " + strings.Join(conds, " AND ")
+	uid := userID(r.Context())
+	conds, args := f.where(uid)
+	if p.after != nil {
+		cond, condArgs, err := order.after(*p.after)
+		if err != nil || p.after.Sort != order.key() {
+			writeError(w, r, errInvalidCursor)
+			return
+		}
+		conds = append(conds, cond)
+		args = append(args, condArgs...)
 	}
 
-	const base = `
-SELECT
-  s.id,
-  s.book_id,
-  b.title,
-  b.author,
-  b.source,
-  s.device_id,
-  s.start_page,
-  s.end_page,
-  s.started_at,
-  s.ended_at,
-  s.duration_seconds,
-  s.created_at,
-  CASE WHEN s.ended_at IS NULL THEN 'open' ELSE 'closed' END AS status,
-  COALESCE(s.ended_at, s.started_at) AS last_activity
+	query := sessionSelect + `,
+  ` + order.expr + ` AS sort_key
 FROM sessions s
 JOIN books b ON b.id = s.book_id
-`
-	query := base + where + `
-ORDER BY last_activity DESC, s.id DESC
-LIMIT ? OFFSET ?;`
+WHERE ` + strings.Join(conds, " AND ") + `
+` + order.orderBy() + `
+LIMIT ?;`
 
-	argsQ := append(args, limit, offset)
-
-	rows, err := a.DB.Query(query, argsQ...)
+	rows, err := a.Store.Query(r.Context(), query, append(args, p.limit+1)...)
 	if err != nil {
-		writeErr(w, http.StatusInternalServerError, "query failed")
+		serverError(w, r, err, "query failed")
 		return
 	}
 	defer rows.Close()
 
-	items := make([]sessionListItem, 0, limit)
+	items := make([]sessionListItem, 0, p.limit+1)
 	for rows.Next() {
 		var it sessionListItem
-		if err := rows.Scan(
-			&it.ID,
-			&it.BookID,
-			&it.BookTitle,
-			&it.Author,
-			&it.Source,
-			&it.DeviceID,
-			&it.StartPage,
-			&it.EndPage,
-			&it.StartedAt,
-			&it.EndedAt,
-			&it.DurationSeconds,
-			&it.CreatedAt,
-			&it.Status,
-			&it.LastActivity,
-		); err != nil {
-			writeErr(w, http.StatusInternalServerError, "scan failed")
+		if err := rows.Scan(append(it.fields(), &it.sortKey)...); err != nil {
+			serverError(w, r, err, "scan failed")
 			return
 		}
 		items = append(items, it)
 	}
 	if err := rows.Err(); err != nil {
-		writeErr(w, http.StatusInternalServerError, "row error")
+		serverError(w, r, err, "row error")
 		return
 	}
 
-	total, err := countSessions(a, device, bookTitle)
-	if err != nil {
-		writeErr(w, http.StatusInternalServerError, "count failed")
-		return
+	next := nextPage(w, r, p, &items, func(it sessionListItem) cursor {
+		return cursor{Sort: order.key(), Key: it.sortKey, ID: it.ID}
+	})
+	meta := map[string]any{
+		"limit":       p.limit,
+		"count":       len(items),
+		"next_cursor": next,
+		"device_id":   f.DeviceID,
+		"book_title":  f.BookTitle,
+		"sort":        order.name,
+		"order":       order.order(),
+	}
+	if p.withTotal {
+		total, err := countSessions(r.Context(), a.Store, uid, f)
+		if err != nil {
+			serverError(w, r, err, "count failed")
+			return
+		}
+		meta["total"] = total
 	}
 
 	writeJSON(w, http.StatusOK, map[string]any{
 		"items": items,
-		"meta": map[string]any{
-			"limit":      limit,
-			"offset":     offset,
-			"count":      len(items),
-			"total":      total,
-			"device_id":  device,
-			"book_title": bookTitle,
-		},
+		"meta":  meta,
 	})
 }
 
-func countSessions(a *App, device, bookTitle string) (int, error) {
-	conds := make([]string, 0, 2)
-	args := make([]any, 0, 2)
-
-	if device != "" {
-		conds = append(conds, "device_id = ?")
-		args = append(args, device)
-	}
-	if bookTitle != "" {
-		conds = append(conds, "book_id IN (SELECT id FROM books WHERE title = ?)")
-		args = append(args, bookTitle)
+// sessionSelect lists the columns sessionListItem.fields scans, for a query
+// over sessions s JOIN books b.
+const sessionSelect = `
+SELECT
+  s.id,
+  s.book_id,
+  b.title,
+  b.author,
+  b.source,
+  s.device_id,
+  s.start_page,
+  s.end_page,
+  s.started_at,
+  s.ended_at,
+  s.duration_seconds,
+  s.created_at,
+  CASE WHEN s.ended_at IS NULL THEN 'open' ELSE 'closed' END AS status,
+  COALESCE(s.ended_at, s.started_at) AS last_activity`
+
+func (it *sessionListItem) fields() []any {
+	return []any{
+		&it.ID,
+		&it.BookID,
+		&it.BookTitle,
+		&it.Author,
+		&it.Source,
+		&it.DeviceID,
+		&it.StartPage,
+		&it.EndPage,
+		&it.StartedAt,
+		&it.EndedAt,
+		&it.DurationSeconds,
+		&it.CreatedAt,
+		&it.Status,
+		&it.LastActivity,
 	}
+}
 
-	sql := `SELECT COUNT(*) FROM sessions`
-	if len(conds) > 0 {
-		sql += " WHERE " + strings.Join(conds, " AND ")
-	}
+func countSessions(ctx context.Context, q Querier, userID int64, f sessionFilter) (int, error) {
+	conds, args := f.where(userID)
+	query := `SELECT COUNT(*) FROM sessions s JOIN books b ON b.id = s.book_id WHERE ` + strings.Join(conds, " AND ")
 
 	var n int
-	if err := a.DB.QueryRow(sql, args...).Scan(&n); err != nil {
+	if err := q.QueryRow(ctx, query, args...).Scan(&n); err != nil {
 		return 0, err
 	}
 	return n, nil
@@ -0,0 +1,180 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type sessionPage struct {
+	Items []struct {
+		ID int64 `json:"id"`
+	} `json:"items"`
+	Meta struct {
+		Count      int     `json:"count"`
+		Total      *int    `json:"total"`
+		NextCursor *string `json:"next_cursor"`
+	} `json:"meta"`
+}
+
+func getSessionPage(t *testing.T, r http.Handler, path string) (sessionPage, string) {
+	t.Helper()
+	w := doJSON(t, r, http.MethodGet, path, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s expected 200, got %d body=%s", path, w.Code, w.Body.String())
+	}
+	var p sessionPage
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return p, w.Header().Get("Link")
+}
+
+func TestListSessions_CursorPagination(t *testing.T) {
+	r := newTestServer(t)
+	// Two sessions end at the same moment, so the id has to break the tie.
+	readSession(t, r, "ipad", "Dune", 0, 10, "2025-09-01T10:00:00Z", "2025-09-01T10:30:00Z")
+	readSession(t, r, "ipad", "Dune", 10, 20, "2025-09-02T10:00:00Z", "2025-09-02T10:30:00Z")
+	readSession(t, r, "kindle", "Emma", 0, 10, "2025-09-02T10:00:00Z", "2025-09-02T10:30:00Z")
+	readSession(t, r, "ipad", "Dune", 20, 30, "2025-09-03T10:00:00Z", "2025-09-03T10:30:00Z")
+	readSession(t, r, "ipad", "Dune", 30, 40, "2025-09-04T10:00:00Z", "2025-09-04T10:30:00Z")
+
+	first, link := getSessionPage(t, r, "/v1/sessions?limit=2")
+	if first.Meta.Count != 2 || first.Meta.NextCursor == nil || first.Meta.Total != nil {
+		t.Fatalf("unexpected first page meta: %+v", first.Meta)
+	}
+	if !strings.Contains(link, `rel="next"`) || !strings.Contains(link, "cursor="+*first.Meta.NextCursor) {
+		t.Fatalf("expected a next Link, got %q", link)
+	}
+
+	// A session read while the client scrolls lands on top and must not
+	// shift the rows of later pages.
+	readSession(t, r, "ipad", "Dune", 40, 50, "2025-09-05T10:00:00Z", "2025-09-05T10:30:00Z")
+
+	seen := map[int64]bool{}
+	var order []int64
+	p, next := first, first.Meta.NextCursor
+	for {
+		for _, it := range p.Items {
+			if seen[it.ID] {
+				t.Fatalf("session %d returned twice", it.ID)
+			}
+			seen[it.ID] = true
+			order = append(order, it.ID)
+		}
+		if next == nil {
+			break
+		}
+		p, link = getSessionPage(t, r, "/v1/sessions?limit=2&cursor="+*next)
+		next = p.Meta.NextCursor
+		if (next == nil) != (link == "") {
+			t.Fatalf("Link header %q does not match next_cursor %v", link, next)
+		}
+	}
+	want := []int64{5, 4, 3, 2, 1}
+	if len(order) != len(want) {
+		t.Fatalf("expected sessions %v, got %v", want, order)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("expected sessions %v, got %v", want, order)
+		}
+	}
+
+	withTotal, _ := getSessionPage(t, r, "/v1/sessions?limit=2&include_total=true")
+	if withTotal.Meta.Total == nil || *withTotal.Meta.Total != 6 {
+		t.Fatalf("expected total 6, got %v", withTotal.Meta.Total)
+	}
+
+	w := doJSON(t, r, http.MethodGet, "/v1/sessions?cursor=not-a-cursor", nil)
+	if w.Code != http.StatusBadRequest || decodeError(t, w).Error.Code != "invalid_value" {
+		t.Fatalf("expected 400 invalid_value for a bad cursor, got %d body=%s", w.Code, w.Body.String())
+	}
+}
+
+func sessionIDs(p sessionPage) []int64 {
+	ids := make([]int64, len(p.Items))
+	for i, it := range p.Items {
+		ids[i] = it.ID
+	}
+	return ids
+}
+
+func TestListSessions_FiltersAndSort(t *testing.T) {
+	r := newTestServer(t)
+	read := func(device, title, author string, startPage int, endPage any, startedAt, endedAt string) {
+		doJSON(t, r, http.MethodPost, "/v1/session/start", map[string]any{"device_id": device, "book_title": title, "author": author, "start_page": startPage, "started_at": startedAt})
+		doJSON(t, r, http.MethodPost, "/v1/session/stop", map[string]any{"device_id": device, "end_page": endPage, "ended_at": endedAt})
+	}
+	read("ipad", "Dune", "Frank Herbert", 0, 10, "2025-09-01T10:00:00Z", "2025-09-01T10:30:00Z")                 // 1: 30 min, 10 pages
+	read("ipad", "Dune", "", 10, 50, "2025-09-02T10:00:00Z", "2025-09-02T11:00:00Z")                             // 2: 60 min, 40 pages
+	read("kindle", "Emma", "Jane Austen", 0, 5, "2025-09-03T10:00:00Z", "2025-09-03T10:10:00Z")                  // 3: 10 min, 5 pages
+	read("kindle", "Emma", "", 5, 25, "2025-09-10T23:30:00Z", "2025-09-11T00:30:00Z")                            // 4: 60 min, 20 pages
+	read("phone", "Emma", "", 0, nil, "2025-09-12T08:00:00Z", "2025-09-12T08:20:00Z")                            // 5: no end_page
+	doJSON(t, r, http.MethodPost, "/v1/session/start", map[string]any{"device_id": "mac", "book_title": "Dune"}) // 6: open
+
+	cases := []struct {
+		query string
+		want  []int64
+	}{
+		{"status=closed&author=austen&sort=started_at&order=asc", []int64{3, 4, 5}},
+		{"author=%25", nil},         // wildcards match themselves
+		{"author=jane_austen", nil}, // not "jane austen"
+		{"book_id=1&sort=started_at&order=asc", []int64{1, 2, 6}},
+		{"status=open", []int64{6}},
+		{"from=2025-09-02&to=2025-09-10&sort=started_at&order=asc", []int64{2, 3, 4}},
+		{"date=ended_at&from=2025-09-11&to=2025-09-11", []int64{4}},
+		{"min_duration=1800&max_duration=3600&sort=duration", []int64{4, 2, 1}},
+		{"has_end_page=false&status=closed", []int64{5}},
+		{"status=closed&sort=pages", []int64{2, 4, 1, 3, 5}},
+		{"status=closed&sort=pages&order=asc", []int64{5, 3, 1, 4, 2}},
+	}
+	for _, tc := range cases {
+		p, _ := getSessionPage(t, r, "/v1/sessions?"+tc.query)
+		got := sessionIDs(p)
+		if len(got) != len(tc.want) {
+			t.Errorf("%s: expected %v, got %v", tc.query, tc.want, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Errorf("%s: expected %v, got %v", tc.query, tc.want, got)
+				break
+			}
+		}
+	}
+
+	// Paging through a numeric sort gives the same order as one big page.
+	var paged []int64
+	path := "/v1/sessions?status=closed&sort=pages&limit=2"
+	for path != "" {
+		p, _ := getSessionPage(t, r, path)
+		paged = append(paged, sessionIDs(p)...)
+		path = ""
+		if p.Meta.NextCursor != nil {
+			path = "/v1/sessions?status=closed&sort=pages&limit=2&cursor=" + *p.Meta.NextCursor
+		}
+	}
+	if len(paged) != 5 || paged[0] != 2 || paged[4] != 5 {
+		t.Fatalf("unexpected paged order %v", paged)
+	}
+
+	withTotal, _ := getSessionPage(t, r, "/v1/sessions?device_id=kindle&include_total=true&limit=1")
+	if withTotal.Meta.Total == nil || *withTotal.Meta.Total != 2 {
+		t.Fatalf("expected a filtered total of 2, got %v", withTotal.Meta.Total)
+	}
+
+	// A cursor only works with the sort it came from.
+	first, _ := getSessionPage(t, r, "/v1/sessions?limit=1")
+	w := doJSON(t, r, http.MethodGet, "/v1/sessions?sort=duration&cursor="+*first.Meta.NextCursor, nil)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a cursor from another sort, got %d", w.Code)
+	}
+
+	for _, q := range []string{"status=paused", "sort=title", "order=up", "min_duration=-1", "has_end_page=maybe", "from=2025-09-10&to=2025-09-01"} {
+		if w := doJSON(t, r, http.MethodGet, "/v1/sessions?"+q, nil); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", q, w.Code)
+		}
+	}
+}
@@ -1,32 +1,31 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"net/http"
-	"strings"
 )
 
 func (a *App) openSession(w http.ResponseWriter, r *http.Request) {
-	deviceID := strings.TrimSpace(r.URL.Query().Get("device_id"))
-	if deviceID == "" {
-		writeErr(w, http.StatusBadRequest, "device_id is required")
+	deviceID, ok := a.resolveDevice(w, r, r.URL.Query().Get("device_id"))
+	if !ok {
 		return
 	}
 
+	uid := userID(r.Context())
 	var out sessionResponse
 
-	err := withTx(a.DB, func(tx *sql.Tx) error {
-		id, bookID, startPage, startedAt, createdAt, err := openSessionByDevice(tx, deviceID)
+	err := a.Store.WithTx(r.Context(), func(ctx context.Context, tx Querier) error {
+		id, bookID, startPage, startedAt, createdAt, err := openSessionByDevice(ctx, tx, uid, deviceID)
 		if err != nil {
 			if errors.Is(err, sql.ErrNoRows) {
-				writeErr(w, http.StatusNotFound, "no open session for this device")
-				return errors.New("notfound")
+				return ErrNoOpenSession
 			}
 			return err
 		}
 
-		title, author, source, err := getBookInfo(tx, bookID)
+		title, author, source, err := getBookInfo(ctx, tx, bookID)
 		if err != nil {
 			return err
 		}
@@ -46,10 +45,7 @@ func (a *App) openSession(w http.ResponseWriter, r *http.Request) {
 	})
 
 	if err != nil {
-		if err.Error() == "notfound" {
-			return
-		}
-		writeErr(w, http.StatusInternalServerError, "internal error")
+		writeError(w, r, err)
 		return
 	}
 
@@ -6,13 +6,11 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
-
-	"github.com/mk-slmn/booksmart/services/api/handlers"
 )
 
 func TestOpenSession_ReturnsOpen(t *testing.T) {
 	db := newTestDB(t)
-	r := handlers.NewServer(db)
+	r := newTestRouter(db)
 
 	start := map[string]any{
 		"device_id":  "phone",
@@ -39,7 +37,7 @@ func TestOpenSession_ReturnsOpen(t *testing.T) {
 
 func TestOpenSession_404WhenNone(t *testing.T) {
 	db := newTestDB(t)
-	r := handlers.NewServer(db)
+	r := newTestRouter(db)
 
 	req := httptest.NewRequest(http.MethodGet, "/v1/sessions/open?device_id=unknown", nil)
 	w := httptest.NewRecorder()
@@ -52,7 +50,7 @@ func TestOpenSession_404WhenNone(t *testing.T) {
 
 func TestOpenSession_400WhenMissingDeviceID(t *testing.T) {
 	db := newTestDB(t)
-	r := handlers.NewServer(db)
+	r := newTestRouter(db)
 
 	req := httptest.NewRequest(http.MethodGet, "/v1/sessions/open", nil)
 	w := httptest.NewRecorder()
@@ -1,97 +1,164 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
-	"errors"
+	"strings"
 )
 
 // -- Books --
-func findBookIDByTitle(tx *sql.Tx, title string) (int64, error) {
-	var id int64
-	err := tx.QueryRow(`SELECT id FROM books WHERE title = ?`, title).Scan(&id)
+func findBookIDByTitle(ctx context.Context, tx Querier, userID int64, title string) (id int64, err error) {
+	ctx, span := startSpan(ctx, "findBookIDByTitle")
+	defer func() { endSpan(span, err) }()
+	err = tx.QueryRow(ctx, `SELECT id FROM books WHERE user_id = ? AND title = ?`, userID, title).Scan(&id)
 	if err == sql.ErrNoRows {
 		return 0, nil
 	}
 	return id, err
 }
 
-func insertBook(tx *sql.Tx, title string, author, source *string, createdAt string) (int64, error) {
-	res, err := tx.Exec(`
-		INSERT INTO books (title, author, source, created_at)
-		VALUES (?, ?, ?, ?)
-	`, title, author, source, createdAt)
-	if err != nil {
-		return 0, err
+func insertBook(ctx context.Context, tx Querier, userID int64, title string, author, source *string, totalPages *int, createdAt string) (id int64, err error) {
+	ctx, span := startSpan(ctx, "insertBook")
+	defer func() { endSpan(span, err) }()
+	err = tx.QueryRow(ctx, `
+		INSERT INTO books (user_id, title, author, source, total_pages, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`, userID, title, author, source, totalPages, createdAt).Scan(&id)
+	return id, err
+}
+
+func findBookByAssetID(ctx context.Context, tx Querier, userID int64, assetID string) (id int64, title string, err error) {
+	ctx, span := startSpan(ctx, "findBookByAssetID")
+	defer func() { endSpan(span, err) }()
+	err = tx.QueryRow(ctx, `SELECT id, title FROM books WHERE user_id = ? AND asset_id = ?`, userID, assetID).Scan(&id, &title)
+	if err == sql.ErrNoRows {
+		return 0, "", nil
+	}
+	return id, title, err
+}
+
+// setBookAssetID links a book to an Apple Books asset unless it already is.
+func setBookAssetID(ctx context.Context, tx Querier, bookID int64, assetID string) (err error) {
+	ctx, span := startSpan(ctx, "setBookAssetID")
+	defer func() { endSpan(span, err) }()
+	_, err = tx.Exec(ctx, `UPDATE books SET asset_id = ? WHERE id = ? AND asset_id IS NULL`, assetID, bookID)
+	return err
+}
+
+func setBookTotalPages(ctx context.Context, tx Querier, bookID int64, totalPages int) (err error) {
+	ctx, span := startSpan(ctx, "setBookTotalPages")
+	defer func() { endSpan(span, err) }()
+	_, err = tx.Exec(ctx, `UPDATE books SET total_pages = ? WHERE id = ?`, totalPages, bookID)
+	return err
+}
+
+// setBookCatalog stores c on the book. With fill, only columns that are
+// still NULL are set, so what the user already has wins.
+func setBookCatalog(ctx context.Context, tx Querier, bookID int64, c bookCatalog, fill bool) (err error) {
+	ctx, span := startSpan(ctx, "setBookCatalog")
+	defer func() { endSpan(span, err) }()
+	set := make([]string, len(bookCatalogFields))
+	for i, col := range bookCatalogFields {
+		if fill {
+			set[i] = col + " = COALESCE(" + col + ", ?)"
+		} else {
+			set[i] = col + " = ?"
+		}
 	}
-	return res.LastInsertId()
+	_, err = tx.Exec(ctx, `UPDATE books SET `+strings.Join(set, ", ")+` WHERE id = ?`, append(c.args(), bookID)...)
+	return err
 }
 
-func getBookInfo(tx *sql.Tx, bookID int64) (title string, author, source *string, err error) {
-	err = tx.QueryRow(`SELECT title, author, source FROM books WHERE id = ?`, bookID).
+func getBookInfo(ctx context.Context, tx Querier, bookID int64) (title string, author, source *string, err error) {
+	ctx, span := startSpan(ctx, "getBookInfo")
+	defer func() { endSpan(span, err) }()
+	err = tx.QueryRow(ctx, `SELECT title, author, source FROM books WHERE id = ?`, bookID).
 		Scan(&title, &author, &source)
 	return
 }
 
 // -- Sessions --
-func openSessionIDByDevice(tx *sql.Tx, deviceID string) (int64, error) {
-	var id int64
-	err := tx.QueryRow(`
+func openSessionIDByDevice(ctx context.Context, tx Querier, userID int64, deviceID string) (id int64, err error) {
+	ctx, span := startSpan(ctx, "openSessionIDByDevice")
+	defer func() { endSpan(span, err) }()
+	err = tx.QueryRow(ctx, `
 		SELECT id
 		FROM sessions
-		WHERE device_id = ? AND ended_at IS NULL
+		WHERE user_id = ? AND device_id = ? AND ended_at IS NULL
 		LIMIT 1
-	`, deviceID).Scan(&id)
+	`, userID, deviceID).Scan(&id)
 	if err == sql.ErrNoRows {
 		return 0, nil
 	}
 	return id, err
 }
 
-func openSessionByDevice(tx *sql.Tx, deviceID string) (id int64, bookID int64, startPage int, startedAt, createdAt string, err error) {
-	err = tx.QueryRow(`
+func openSessionByDevice(ctx context.Context, tx Querier, userID int64, deviceID string) (id int64, bookID int64, startPage int, startedAt, createdAt string, err error) {
+	ctx, span := startSpan(ctx, "openSessionByDevice")
+	defer func() { endSpan(span, err) }()
+	err = tx.QueryRow(ctx, `
 		SELECT id, book_id, start_page, started_at, created_at
 		FROM sessions
-		WHERE device_id = ? AND ended_at IS NULL
+		WHERE user_id = ? AND device_id = ? AND ended_at IS NULL
 		LIMIT 1
-	`, deviceID).Scan(&id, &bookID, &startPage, &startedAt, &createdAt)
+	`, userID, deviceID).Scan(&id, &bookID, &startPage, &startedAt, &createdAt)
 	return
 }
 
-func mostRecentSessionByDevice(tx *sql.Tx, deviceID string) (id int64, bookID int64, startPage int, endPage *int, startedAt, createdAt string, err error) {
-	err = tx.QueryRow(`
+func mostRecentSessionByDevice(ctx context.Context, tx Querier, userID int64, deviceID string) (id int64, bookID int64, startPage int, endPage *int, startedAt, createdAt string, err error) {
+	ctx, span := startSpan(ctx, "mostRecentSessionByDevice")
+	defer func() { endSpan(span, err) }()
+	err = tx.QueryRow(ctx, `
 		SELECT id, book_id, start_page, end_page, started_at, created_at
 		FROM sessions
-		WHERE device_id = ?
+		WHERE user_id = ? AND device_id = ?
 		ORDER BY started_at DESC
 		LIMIT 1
-	`, deviceID).Scan(&id, &bookID, &startPage, &endPage, &startedAt, &createdAt)
+	`, userID, deviceID).Scan(&id, &bookID, &startPage, &endPage, &startedAt, &createdAt)
 	return
 }
 
-func insertSession(tx *sql.Tx, bookID int64, deviceID string, startPage int, startedAt, createdAt string) (int64, error) {
-	res, err := tx.Exec(`
-		INSERT INTO sessions (book_id, device_id, start_page, started_at, created_at)
-		VALUES (?, ?, ?, ?, ?)
-	`, bookID, deviceID, startPage, startedAt, createdAt)
-	if err != nil {
-		return 0, err
-	}
-	return res.LastInsertId()
+func insertSession(ctx context.Context, tx Querier, userID, bookID int64, deviceID string, startPage int, startedAt, createdAt string) (id int64, err error) {
+	ctx, span := startSpan(ctx, "insertSession")
+	defer func() { endSpan(span, err) }()
+	err = tx.QueryRow(ctx, `
+		INSERT INTO sessions (user_id, book_id, device_id, start_page, started_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`, userID, bookID, deviceID, startPage, startedAt, createdAt).Scan(&id)
+	return id, err
+}
+
+// insertImportedSession inserts a session from an import with every column
+// given; it may be open or closed.
+func insertImportedSession(ctx context.Context, tx Querier, userID, bookID int64, deviceID string, startPage int, endPage *int,
+	startedAt string, endedAt *string, durationSeconds *int64, createdAt string) (id int64, err error) {
+	ctx, span := startSpan(ctx, "insertImportedSession")
+	defer func() { endSpan(span, err) }()
+	err = tx.QueryRow(ctx, `
+		INSERT INTO sessions (user_id, book_id, device_id, start_page, end_page, started_at, ended_at, duration_seconds, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`, userID, bookID, deviceID, startPage, endPage, startedAt, endedAt, durationSeconds, createdAt).Scan(&id)
+	return id, err
 }
 
-func closeSession(tx *sql.Tx, id int64, endedAt string, durationSeconds int64, endPage *int) error {
+func closeSession(ctx context.Context, tx Querier, id int64, endedAt string, durationSeconds int64, endPage *int) (err error) {
+	ctx, span := startSpan(ctx, "closeSession")
+	defer func() { endSpan(span, err) }()
 	if endPage != nil {
 		if *endPage < 0 {
-			return errors.New("end_page must be >= 0")
+			return ErrInvalidEndPage.Field("end_page", "end_page must be >= 0")
 		}
-		_, err := tx.Exec(`
+		_, err = tx.Exec(ctx, `
 			UPDATE sessions
 			SET end_page = ?, ended_at = ?, duration_seconds = ?
 			WHERE id = ?
 		`, *endPage, endedAt, durationSeconds, id)
 		return err
 	}
-	_, err := tx.Exec(`
+	_, err = tx.Exec(ctx, `
 		UPDATE sessions
 		SET ended_at = ?, duration_seconds = ?
 		WHERE id = ?
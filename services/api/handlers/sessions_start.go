@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"cmp"
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
@@ -12,23 +14,29 @@ import (
 func (a *App) startSession(w http.ResponseWriter, r *http.Request) {
 	var req startSessionRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeErr(w, http.StatusBadRequest, "invalid JSON body")
+		writeError(w, r, ErrInvalidJSON)
 		return
 	}
 
-	req.DeviceID = strings.TrimSpace(req.DeviceID)
 	req.BookTitle = strings.TrimSpace(req.BookTitle)
+	req.AssetID = strings.TrimSpace(req.AssetID)
 
-	if req.DeviceID == "" {
-		writeErr(w, http.StatusBadRequest, "device_id is required")
+	deviceID, ok := a.resolveDevice(w, r, req.DeviceID)
+	if !ok {
 		return
 	}
-	if req.BookTitle == "" {
-		writeErr(w, http.StatusBadRequest, "book_title is required")
+	req.DeviceID = deviceID
+
+	if req.BookTitle == "" && req.AssetID == "" {
+		writeError(w, r, ErrRequired.Field("book_title", "book_title is required"))
 		return
 	}
 	if req.StartPage < 0 {
-		writeErr(w, http.StatusBadRequest, "start_page must be >= 0")
+		writeError(w, r, ErrInvalidPage.Field("start_page", "start_page must be >= 0"))
+		return
+	}
+	if req.TotalPages != nil && *req.TotalPages <= 0 {
+		writeError(w, r, ErrInvalidPage.Field("total_pages", "total_pages must be > 0"))
 		return
 	}
 
@@ -36,7 +44,7 @@ func (a *App) startSession(w http.ResponseWriter, r *http.Request) {
 	if req.StartedAt != nil && strings.TrimSpace(*req.StartedAt) != "" {
 		t, err := parseRFC3339UTC(*req.StartedAt)
 		if err != nil {
-			writeErr(w, http.StatusBadRequest, "started_at must be RFC3339 (e.g., 2025-09-16T21:25:00Z)")
+			writeError(w, r, ErrInvalidTime.Field("started_at", "started_at must be RFC3339 (e.g., 2025-09-16T21:25:00Z)"))
 			return
 		}
 		startedAt = t.Format(time.RFC3339)
@@ -44,10 +52,13 @@ func (a *App) startSession(w http.ResponseWriter, r *http.Request) {
 		startedAt = timeOrNowRFC3339(nil)
 	}
 
+	uid := userID(r.Context())
 	var out sessionResponse
 
-	err := withTx(a.DB, func(tx *sql.Tx) error {
-		if openID, _, _, openStartedAt, _, err := openSessionByDevice(tx, req.DeviceID); err == nil {
+	var closedPrev, newBook bool
+	err := a.Store.WithTx(r.Context(), func(ctx context.Context, tx Querier) error {
+		closedPrev, newBook = false, false
+		if openID, _, _, openStartedAt, _, err := openSessionByDevice(ctx, tx, uid, req.DeviceID); err == nil {
 			stPrev, err := parseRFC3339UTC(openStartedAt)
 			if err != nil {
 				return err
@@ -62,26 +73,54 @@ func (a *App) startSession(w http.ResponseWriter, r *http.Request) {
 			}
 			sec := int64(dur / time.Second)
 
-			if err := closeSession(tx, openID, startedAt, sec, nil); err != nil {
+			if err := closeSession(ctx, tx, openID, startedAt, sec, nil); err != nil {
 				return err
 			}
+			closedPrev = true
 		} else if !errors.Is(err, sql.ErrNoRows) {
 			return err
 		}
 
-		bookID, err := findBookIDByTitle(tx, req.BookTitle)
-		if err != nil {
-			return err
+		title := req.BookTitle
+		var bookID int64
+		if req.AssetID != "" {
+			id, assetTitle, err := findBookByAssetID(ctx, tx, uid, req.AssetID)
+			if err != nil {
+				return err
+			}
+			bookID, title = id, cmp.Or(assetTitle, title)
+		}
+		if title == "" {
+			return ErrRequired.Field("book_title", "book_title is required unless asset_id matches a book")
+		}
+		if bookID == 0 {
+			id, err := findBookIDByTitle(ctx, tx, uid, title)
+			if err != nil {
+				return err
+			}
+			bookID = id
 		}
 		if bookID == 0 {
-			bookID, err = insertBook(tx, req.BookTitle, req.Author, req.Source, timeOrNowRFC3339(nil))
+			id, err := insertBook(ctx, tx, uid, title, req.Author, req.Source, req.TotalPages, timeOrNowRFC3339(nil))
 			if err != nil {
 				return err
 			}
+			bookID = id
+			newBook = true
+		} else if req.TotalPages != nil {
+			if err := setBookTotalPages(ctx, tx, bookID, *req.TotalPages); err != nil {
+				return err
+			}
+		}
+
+		if req.AssetID != "" {
+			if err := setBookAssetID(ctx, tx, bookID, req.AssetID); err != nil {
+				return err
+			}
 		}
 
 		now := timeOrNowRFC3339(nil)
-		id, err := insertSession(tx, bookID, req.DeviceID, req.StartPage, startedAt, now)
+		id, err := insertSession(ctx, tx, uid, bookID, req.DeviceID, req.StartPage, startedAt, now)
 		if err != nil {
 			return err
 		}
@@ -93,7 +132,7 @@ func (a *App) startSession(w http.ResponseWriter, r *http.Request) {
 			StartPage: req.StartPage,
 			StartedAt: startedAt,
 			CreatedAt: now,
-			BookTitle: req.BookTitle,
+			BookTitle: title,
 			Author:    req.Author,
 			Source:    req.Source,
 		}
@@ -101,9 +140,16 @@ func (a *App) startSession(w http.ResponseWriter, r *http.Request) {
 	})
 
 	if err != nil {
-		writeErr(w, http.StatusInternalServerError, "internal error")
+		writeError(w, r, err)
 		return
 	}
 
+	a.metrics.sessionsStarted.WithLabelValues("start").Inc()
+	if closedPrev {
+		a.metrics.sessionsStopped.WithLabelValues("start").Inc()
+	}
+	if newBook {
+		a.metrics.booksCreated.Inc()
+	}
 	writeJSON(w, http.StatusCreated, out)
 }
@@ -6,13 +6,11 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
-
-	"github.com/mk-slmn/booksmart/services/api/handlers"
 )
 
 func TestSessionStart_CreatesBookAndSession(t *testing.T) {
 	db := newTestDB(t)
-	r := handlers.NewServer(db)
+	r := newTestRouter(db)
 
 	body := map[string]any{
 		"device_id":  "iphone-14",
@@ -52,7 +50,7 @@ func TestSessionStart_CreatesBookAndSession(t *testing.T) {
 
 func TestSessionStart_ConflictIfOpenSessionExists(t *testing.T) {
 	db := newTestDB(t)
-	r := handlers.NewServer(db)
+	r := newTestRouter(db)
 
 	first := map[string]any{
 		"device_id":  "ipad",
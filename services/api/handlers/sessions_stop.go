@@ -1,32 +1,35 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
 )
 
 func (a *App) stopSession(w http.ResponseWriter, r *http.Request) {
 	var req stopSessionRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeErr(w, http.StatusBadRequest, "invalid JSON body")
+		writeError(w, r, ErrInvalidJSON)
 		return
 	}
 
-	req.DeviceID = strings.TrimSpace(req.DeviceID)
-	if req.DeviceID == "" {
-		writeErr(w, http.StatusBadRequest, "device_id is required")
+	deviceID, ok := a.resolveDevice(w, r, req.DeviceID)
+	if !ok {
 		return
 	}
+	req.DeviceID = deviceID
 
 	var endedAt string
 	if req.EndedAt != nil && strings.TrimSpace(*req.EndedAt) != "" {
 		t, err := parseRFC3339UTC(*req.EndedAt)
 		if err != nil {
-			writeErr(w, http.StatusBadRequest, "ended_at must be RFC3339 (e.g., 2025-09-16T21:25:00Z)")
+			writeError(w, r, ErrInvalidTime.Field("ended_at", "ended_at must be RFC3339 (e.g., 2025-09-16T21:25:00Z)"))
 			return
 		}
 		endedAt = t.Format(time.RFC3339)
@@ -34,14 +37,14 @@ func (a *App) stopSession(w http.ResponseWriter, r *http.Request) {
 		endedAt = timeOrNowRFC3339(nil)
 	}
 
+	uid := userID(r.Context())
 	var out sessionResponse
 
-	err := withTx(a.DB, func(tx *sql.Tx) error {
-		id, bookID, startPage, startedAt, createdAt, err := openSessionByDevice(tx, req.DeviceID)
+	err := a.Store.WithTx(r.Context(), func(ctx context.Context, tx Querier) error {
+		id, bookID, startPage, startedAt, createdAt, err := openSessionByDevice(ctx, tx, uid, req.DeviceID)
 		if err != nil {
 			if errors.Is(err, sql.ErrNoRows) {
-				writeErr(w, http.StatusNotFound, "no open session for this device")
-				return errors.New("notfound")
+				return ErrNoOpenSession
 			}
 			return err
 		}
@@ -60,15 +63,11 @@ func (a *App) stopSession(w http.ResponseWriter, r *http.Request) {
 		}
 		sec := int64(dur / time.Second)
 
-		if err := closeSession(tx, id, endedAt, sec, req.EndPage); err != nil {
-			if err.Error() == "end_page must be >= 0" {
-				writeErr(w, http.StatusBadRequest, "end_page must be >= 0")
-				return errors.New("badend")
-			}
+		if err := closeSession(ctx, tx, id, endedAt, sec, req.EndPage); err != nil {
 			return err
 		}
 
-		title, author, source, err := getBookInfo(tx, bookID)
+		title, author, source, err := getBookInfo(ctx, tx, bookID)
 		if err != nil {
 			return err
 		}
@@ -87,16 +86,35 @@ func (a *App) stopSession(w http.ResponseWriter, r *http.Request) {
 			Author:          author,
 			Source:          source,
 		}
-		return nil
+
+		if req.Note == nil || strings.TrimSpace(*req.Note) == "" {
+			return nil
+		}
+		noteID, err := insertNote(ctx, tx, uid, bookID, &id, noteKindNote, strings.TrimSpace(*req.Note), nil, req.EndPage, endedAt)
+		if err != nil {
+			return err
+		}
+		note, err := findNote(ctx, tx, uid, noteID)
+		out.Note = &note
+		return err
 	})
 
 	if err != nil {
-		if err.Error() == "notfound" || err.Error() == "badend" {
-			return
-		}
-		writeErr(w, http.StatusInternalServerError, "internal error")
+		writeError(w, r, err)
 		return
 	}
+	a.metrics.sessionsStopped.WithLabelValues("stop").Inc()
+
+	// The session is already closed; goal progress is best effort. Periods
+	// follow the stopping device's time zone.
+	loc, err := deviceLocation(r.Context(), a.Store, uid, req.DeviceID)
+	if err == nil {
+		out.GoalProgress, err = goalSummaries(r.Context(), a.Store, uid, time.Now(), loc)
+	}
+	if err != nil {
+		a.logger().WarnContext(r.Context(), "goal progress failed",
+			"request_id", middleware.GetReqID(r.Context()), "session_id", out.ID, "error", err)
+	}
 
 	writeJSON(w, http.StatusOK, out)
 }
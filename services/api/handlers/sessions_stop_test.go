@@ -6,13 +6,11 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
-
-	"github.com/mk-slmn/booksmart/services/api/handlers"
 )
 
 func TestSessionStop_ClosesSessionAndComputesDuration(t *testing.T) {
 	db := newTestDB(t)
-	r := handlers.NewServer(db)
+	r := newTestRouter(db)
 
 	start := map[string]any{
 		"device_id":  "iphone-14",
@@ -66,7 +64,7 @@ func TestSessionStop_ClosesSessionAndComputesDuration(t *testing.T) {
 
 func TestSessionStop_NotFoundIfNoOpenSession(t *testing.T) {
 	db := newTestDB(t)
-	r := handlers.NewServer(db)
+	r := newTestRouter(db)
 
 	stop := map[string]any{
 		"device_id": "ipad",
@@ -1,18 +1,27 @@
 package handlers
 
 type startSessionRequest struct {
-	DeviceID  string  `json:"device_id"`
-	BookTitle string  `json:"book_title"`
-	Author    *string `json:"author,omitempty"`
-	Source    *string `json:"source,omitempty"`
-	StartPage int     `json:"start_page"`
-	StartedAt *string `json:"started_at,omitempty"`
+	DeviceID   string  `json:"device_id"`
+	BookTitle  string  `json:"book_title"`
+	Author     *string `json:"author,omitempty"`
+	Source     *string `json:"source,omitempty"`
+	TotalPages *int    `json:"total_pages,omitempty"`
+	StartPage  int     `json:"start_page"`
+	StartedAt  *string `json:"started_at,omitempty"`
+
+	// AssetID is the Apple Books asset being read. A book with this asset id
+	// is used whatever book_title says, so book_title may then be omitted;
+	// otherwise the book found or created by title is linked to it.
+	AssetID string `json:"asset_id,omitempty"`
 }
 
 type stopSessionRequest struct {
 	DeviceID string  `json:"device_id"`
 	EndPage  *int    `json:"end_page,omitempty"`
 	EndedAt  *string `json:"ended_at,omitempty"`
+
+	// Note, if not blank, is kept as a note on the session at end_page.
+	Note *string `json:"note,omitempty"`
 }
 
 type continueSessionRequest struct {
@@ -33,4 +42,7 @@ type sessionResponse struct {
 	BookTitle       string  `json:"book_title"`
 	Author          *string `json:"author,omitempty"`
 	Source          *string `json:"source,omitempty"`
+
+	Note         *noteItem     `json:"note,omitempty"`
+	GoalProgress []goalSummary `json:"goal_progress,omitempty"`
 }
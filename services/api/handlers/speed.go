@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// speedSample is one closed session that can be used for pages-per-hour
+// figures.
+type speedSample struct {
+	BookID    int64
+	BookTitle string
+	Author    *string
+	DeviceID  string
+	EndedAt   time.Time
+	Pages     int
+	Seconds   int64
+}
+
+type speedStat struct {
+	PagesPerHour *float64 `json:"pages_per_hour"`
+	Pages        int      `json:"pages"`
+	Seconds      int64    `json:"seconds"`
+	Sessions     int      `json:"sessions"`
+}
+
+func (s *speedStat) add(x speedSample) {
+	s.Pages += x.Pages
+	s.Seconds += x.Seconds
+	s.Sessions++
+	s.PagesPerHour = pagesPerHour(s.Pages, s.Seconds)
+}
+
+// loadSpeedSamples returns a user's closed sessions usable for speed
+// figures, oldest first. Sessions without an end_page, with a non-positive
+// duration or with a negative page delta are treated as outliers and skipped.
+// extra is appended to the WHERE clause as-is.
+func loadSpeedSamples(ctx context.Context, q Querier, userID int64, extra string, args ...any) (_ []speedSample, err error) {
+	ctx, span := startSpan(ctx, "loadSpeedSamples")
+	defer func() { endSpan(span, err) }()
+
+	rows, err := q.Query(ctx, `
+SELECT s.book_id, b.title, b.author, s.device_id, s.ended_at,
+       s.end_page - s.start_page, s.duration_seconds
+FROM sessions s
+JOIN books b ON b.id = s.book_id
+WHERE s.user_id = ?
+  AND s.ended_at IS NOT NULL
+  AND s.end_page IS NOT NULL
+  AND s.duration_seconds > 0
+  AND s.end_page >= s.start_page
+`+extra+`
+ORDER BY s.ended_at ASC, s.id ASC;`, append([]any{userID}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []speedSample
+	for rows.Next() {
+		var x speedSample
+		var endedAt string
+		if err := rows.Scan(&x.BookID, &x.BookTitle, &x.Author, &x.DeviceID, &endedAt, &x.Pages, &x.Seconds); err != nil {
+			return nil, err
+		}
+		if x.EndedAt, err = parseRFC3339UTC(endedAt); err != nil {
+			continue
+		}
+		out = append(out, x)
+	}
+	return out, rows.Err()
+}
+
+// pagesPerHour is the ratio of total pages to total time, so long sessions
+// weigh more than short ones. Returns nil when there is no time to divide by.
+func pagesPerHour(pages int, seconds int64) *float64 {
+	if seconds <= 0 {
+		return nil
+	}
+	v := round2(float64(pages) / (float64(seconds) / 3600))
+	return &v
+}
+
+// secondsToFinish estimates the reading time left for remaining pages at the
+// given speed.
+func secondsToFinish(remaining int, pph float64) *int64 {
+	if remaining < 0 || pph <= 0 {
+		return nil
+	}
+	sec := int64(math.Round(float64(remaining) / pph * 3600))
+	return &sec
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
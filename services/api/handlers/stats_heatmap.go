@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+	_ "time/tzdata" // tz= must work on hosts without a zoneinfo database
+)
+
+func (a *App) statsHeatmap(w http.ResponseWriter, r *http.Request) {
+	loc := time.UTC
+	if v := strings.TrimSpace(r.URL.Query().Get("tz")); v != "" {
+		l, err := time.LoadLocation(v)
+		if err != nil {
+			writeError(w, r, ErrInvalidTimeZone.Field("tz", "tz must be an IANA time zone (e.g., Europe/Berlin)"))
+			return
+		}
+		loc = l
+	}
+
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	to := today.AddDate(0, 0, 1)
+	from := to.AddDate(0, 0, -30)
+
+	if v := strings.TrimSpace(r.URL.Query().Get("from")); v != "" {
+		t, err := parseDateOrRFC3339(v, loc, false)
+		if err != nil {
+			writeError(w, r, ErrInvalidTime.Field("from", "from must be YYYY-MM-DD or RFC3339"))
+			return
+		}
+		from = t
+	}
+	if v := strings.TrimSpace(r.URL.Query().Get("to")); v != "" {
+		t, err := parseDateOrRFC3339(v, loc, true)
+		if err != nil {
+			writeError(w, r, ErrInvalidTime.Field("to", "to must be YYYY-MM-DD or RFC3339"))
+			return
+		}
+		to = t
+	}
+	if !from.Before(to) {
+		writeError(w, r, ErrInvalidRange)
+		return
+	}
+
+	rows, err := a.Store.Query(r.Context(), `
+SELECT started_at, ended_at
+FROM sessions
+WHERE user_id = ?
+  AND ended_at IS NOT NULL
+  AND started_at < ?
+  AND ended_at > ?`,
+		userID(r.Context()), to.UTC().Format(time.RFC3339), from.UTC().Format(time.RFC3339))
+	if err != nil {
+		serverError(w, r, err, "query failed")
+		return
+	}
+	defer rows.Close()
+
+	var seconds [7][24]float64
+	for rows.Next() {
+		var startedAt, endedAt string
+		if err := rows.Scan(&startedAt, &endedAt); err != nil {
+			serverError(w, r, err, "scan failed")
+			return
+		}
+		st, err1 := parseRFC3339UTC(startedAt)
+		en, err2 := parseRFC3339UTC(endedAt)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		spreadOverHours(&seconds, st, en, from, to, loc)
+	}
+	if err := rows.Err(); err != nil {
+		serverError(w, r, err, "row error")
+		return
+	}
+
+	minutes := make([][]float64, 7)
+	total := 0.0
+	for d := range seconds {
+		minutes[d] = make([]float64, 24)
+		for h, sec := range seconds[d] {
+			minutes[d][h] = round2(sec / 60)
+			total += sec / 60
+		}
+	}
+	weekdays := make([]string, 7)
+	for d := range weekdays {
+		weekdays[d] = time.Weekday(d).String()
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"from":          from.Format(time.RFC3339),
+		"to":            to.Format(time.RFC3339),
+		"tz":            loc.String(),
+		"weekdays":      weekdays,
+		"minutes":       minutes,
+		"total_minutes": round2(total),
+	})
+}
+
+// spreadOverHours credits the part of [start, end) that falls inside
+// [from, to) to the local weekday/hour cells it actually covers.
+//
+// It steps by instants, to wherever the local clock next shows a full
+// hour. Rebuilding the boundary from the wall clock instead would stall in
+// the hour repeated when DST ends.
+func spreadOverHours(cells *[7][24]float64, start, end, from, to time.Time, loc *time.Location) {
+	if start.Before(from) {
+		start = from
+	}
+	if end.After(to) {
+		end = to
+	}
+	for cur := start; cur.Before(end); {
+		lt := cur.In(loc)
+		intoHour := time.Duration(lt.Minute())*time.Minute + time.Duration(lt.Second())*time.Second + time.Duration(lt.Nanosecond())
+		next := cur.Add(time.Hour - intoHour)
+		if !next.After(cur) {
+			break
+		}
+		if next.After(end) {
+			next = end
+		}
+		cells[lt.Weekday()][lt.Hour()] += next.Sub(cur).Seconds()
+		cur = next
+	}
+}
@@ -0,0 +1,91 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+type heatmapResponse struct {
+	Minutes      [][]float64 `json:"minutes"`
+	TotalMinutes float64     `json:"total_minutes"`
+}
+
+func TestStatsHeatmap_SplitsAcrossHours(t *testing.T) {
+	r := newTestServer(t)
+
+	// Tuesday 20:40–21:20 UTC.
+	readSession(t, r, "phone", "Dune", 0, 10, "2025-09-16T20:40:00Z", "2025-09-16T21:20:00Z")
+
+	w := doJSON(t, r, http.MethodGet, "/v1/stats/heatmap?from=2025-09-01&to=2025-09-30", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", w.Code, w.Body.String())
+	}
+	var resp heatmapResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if len(resp.Minutes) != 7 || len(resp.Minutes[0]) != 24 {
+		t.Fatalf("expected 7x24 matrix, got %d rows", len(resp.Minutes))
+	}
+	if resp.Minutes[2][20] != 20 || resp.Minutes[2][21] != 20 || resp.TotalMinutes != 40 {
+		t.Fatalf("unexpected cells: 20h=%v 21h=%v total=%v", resp.Minutes[2][20], resp.Minutes[2][21], resp.TotalMinutes)
+	}
+
+	// Same session seen from UTC+5:30 is Wednesday 02:10–02:50.
+	w = doJSON(t, r, http.MethodGet, "/v1/stats/heatmap?from=2025-09-01&to=2025-09-30&tz=Asia/Kolkata", nil)
+	resp = heatmapResponse{}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Minutes[3][2] != 40 {
+		t.Fatalf("expected 40 minutes on Wednesday 02h, got %v", resp.Minutes[3][2])
+	}
+}
+
+func TestStatsHeatmap_DSTTransitions(t *testing.T) {
+	r := newTestServer(t)
+
+	// Berlin falls back at 01:00 UTC on 2025-10-26 (03:00 CEST → 02:00 CET),
+	// so this session runs 02:30 CEST → 03:30 CET through the repeated hour.
+	readSession(t, r, "phone", "Dune", 0, 10, "2025-10-26T00:30:00Z", "2025-10-26T02:30:00Z")
+	// Berlin springs forward at 01:00 UTC on 2025-03-30 (02:00 CET → 03:00
+	// CEST): 01:30 CET → 03:30 CEST, with no 02h at all.
+	readSession(t, r, "tablet", "Emma", 0, 10, "2025-03-30T00:30:00Z", "2025-03-30T01:30:00Z")
+
+	cases := []struct {
+		name, from, to string
+		cells          map[int]float64 // Sunday hour → minutes
+		total          float64
+	}{
+		{"fall back", "2025-10-01", "2025-10-31", map[int]float64{1: 0, 2: 90, 3: 30}, 120},
+		{"spring forward", "2025-03-01", "2025-03-31", map[int]float64{1: 30, 2: 0, 3: 30}, 60},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := doJSON(t, r, http.MethodGet, "/v1/stats/heatmap?from="+tc.from+"&to="+tc.to+"&tz=Europe/Berlin", nil)
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %d body=%s", w.Code, w.Body.String())
+			}
+			var resp heatmapResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("invalid json: %v", err)
+			}
+			for hour, want := range tc.cells {
+				if got := resp.Minutes[0][hour]; got != want {
+					t.Fatalf("Sunday %02dh: expected %v minutes, got %v", hour, want, got)
+				}
+			}
+			if resp.TotalMinutes != tc.total {
+				t.Fatalf("expected %v minutes in total, got %v", tc.total, resp.TotalMinutes)
+			}
+		})
+	}
+}
+
+func TestStatsHeatmap_400OnBadTZ(t *testing.T) {
+	r := newTestServer(t)
+
+	w := doJSON(t, r, http.MethodGet, "/v1/stats/heatmap?tz=Mars/Olympus", nil)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d body=%s", w.Code, w.Body.String())
+	}
+}
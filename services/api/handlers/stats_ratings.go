@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"net/http"
+)
+
+type ratingGroup struct {
+	Books   int     `json:"books"`
+	Average float64 `json:"average"`
+}
+
+type authorRating struct {
+	Author string `json:"author"`
+	ratingGroup
+}
+
+type yearRating struct {
+	Year string `json:"year"`
+	ratingGroup
+}
+
+// statsRatings averages the user's ratings overall, by author and by year.
+// A book's year is the year it was finished, or else reviewed; rated books
+// without either only count overall.
+func (a *App) statsRatings(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	uid := userID(ctx)
+
+	var overall ratingGroup
+	var avg *float64
+	if err := a.Store.QueryRow(ctx, `SELECT COUNT(*), AVG(rating) FROM books WHERE user_id = ? AND rating IS NOT NULL`, uid).
+		Scan(&overall.Books, &avg); err != nil {
+		serverError(w, r, err, "query failed")
+		return
+	}
+	if avg != nil {
+		overall.Average = round2(*avg)
+	}
+
+	byAuthor := make([]authorRating, 0)
+	err := scanAll(ctx, a.Store, &byAuthor,
+		func(it *authorRating) []any { return []any{&it.Author, &it.Books, &it.Average} }, `
+SELECT author, COUNT(*), AVG(rating) AS average
+FROM books
+WHERE user_id = ? AND rating IS NOT NULL AND author IS NOT NULL
+GROUP BY author
+ORDER BY average DESC, COUNT(*) DESC, author`, uid)
+	if err != nil {
+		serverError(w, r, err, "query failed")
+		return
+	}
+
+	byYear := make([]yearRating, 0)
+	err = scanAll(ctx, a.Store, &byYear,
+		func(it *yearRating) []any { return []any{&it.Year, &it.Books, &it.Average} }, `
+SELECT SUBSTR(COALESCE(finished_on, reviewed_at), 1, 4) AS year, COUNT(*), AVG(rating)
+FROM books
+WHERE user_id = ? AND rating IS NOT NULL AND COALESCE(finished_on, reviewed_at) IS NOT NULL
+GROUP BY year
+ORDER BY year`, uid)
+	if err != nil {
+		serverError(w, r, err, "query failed")
+		return
+	}
+
+	for i := range byAuthor {
+		byAuthor[i].Average = round2(byAuthor[i].Average)
+	}
+	for i := range byYear {
+		byYear[i].Average = round2(byYear[i].Average)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"rated_books": overall.Books,
+		"average":     overall.Average,
+		"by_author":   byAuthor,
+		"by_year":     byYear,
+	})
+}
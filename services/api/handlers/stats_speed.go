@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+type bookSpeed struct {
+	BookID    int64   `json:"book_id"`
+	BookTitle string  `json:"book_title"`
+	Author    *string `json:"author,omitempty"`
+	speedStat
+}
+
+type deviceSpeed struct {
+	DeviceID string `json:"device_id"`
+	speedStat
+}
+
+type speedDay struct {
+	DayISO       string   `json:"day_iso"`
+	PagesPerHour *float64 `json:"pages_per_hour"`
+	Sessions     int      `json:"sessions"`
+}
+
+func (a *App) statsSpeed(w http.ResponseWriter, r *http.Request) {
+	days := 30
+	if v := r.URL.Query().Get("days"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			if n > 365 {
+				n = 365
+			}
+			days = n
+		}
+	}
+	window := 7
+	if v := r.URL.Query().Get("window"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			if n > 30 {
+				n = 30
+			}
+			window = n
+		}
+	}
+
+	samples, err := loadSpeedSamples(r.Context(), a.Store, userID(r.Context()), "")
+	if err != nil {
+		serverError(w, r, err, "query failed")
+		return
+	}
+
+	var overall speedStat
+	books := map[int64]*bookSpeed{}
+	devices := map[string]*deviceSpeed{}
+	for _, x := range samples {
+		overall.add(x)
+
+		b, ok := books[x.BookID]
+		if !ok {
+			b = &bookSpeed{BookID: x.BookID, BookTitle: x.BookTitle, Author: x.Author}
+			books[x.BookID] = b
+		}
+		b.add(x)
+
+		d, ok := devices[x.DeviceID]
+		if !ok {
+			d = &deviceSpeed{DeviceID: x.DeviceID}
+			devices[x.DeviceID] = d
+		}
+		d.add(x)
+	}
+
+	byBook := make([]bookSpeed, 0, len(books))
+	for _, b := range books {
+		byBook = append(byBook, *b)
+	}
+	sort.Slice(byBook, func(i, j int) bool {
+		if byBook[i].Seconds != byBook[j].Seconds {
+			return byBook[i].Seconds > byBook[j].Seconds
+		}
+		return byBook[i].BookID < byBook[j].BookID
+	})
+
+	byDevice := make([]deviceSpeed, 0, len(devices))
+	for _, d := range devices {
+		byDevice = append(byDevice, *d)
+	}
+	sort.Slice(byDevice, func(i, j int) bool {
+		if byDevice[i].Seconds != byDevice[j].Seconds {
+			return byDevice[i].Seconds > byDevice[j].Seconds
+		}
+		return byDevice[i].DeviceID < byDevice[j].DeviceID
+	})
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"overall":   overall,
+		"by_book":   byBook,
+		"by_device": byDevice,
+		"trend": map[string]any{
+			"range_days":  days,
+			"window_days": window,
+			"items":       speedTrend(samples, time.Now().UTC(), days, window),
+		},
+	})
+}
+
+// speedTrend returns one entry per UTC day, newest first, each covering the
+// samples that ended in the trailing window of days up to and including it.
+func speedTrend(samples []speedSample, now time.Time, days, window int) []speedDay {
+	today := now.Truncate(24 * time.Hour)
+	out := make([]speedDay, 0, days)
+	for i := 0; i < days; i++ {
+		dayEnd := today.AddDate(0, 0, 1-i)
+		dayStart := dayEnd.AddDate(0, 0, -window)
+
+		var st speedStat
+		for _, x := range samples {
+			if !x.EndedAt.Before(dayStart) && x.EndedAt.Before(dayEnd) {
+				st.add(x)
+			}
+		}
+		out = append(out, speedDay{
+			DayISO:       dayEnd.AddDate(0, 0, -1).Format(time.DateOnly),
+			PagesPerHour: st.PagesPerHour,
+			Sessions:     st.Sessions,
+		})
+	}
+	return out
+}
@@ -0,0 +1,51 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestStatsSpeed_ExcludesOutliers(t *testing.T) {
+	r := newTestServer(t)
+
+	// 30 pages in 30 minutes, then 30 pages in 90 minutes on another device.
+	readSession(t, r, "phone", "Dune", 0, 30, "2025-09-16T20:00:00Z", "2025-09-16T20:30:00Z")
+	readSession(t, r, "ipad", "Dune", 30, 60, "2025-09-17T20:00:00Z", "2025-09-17T21:30:00Z")
+	// Negative delta and zero duration are ignored.
+	readSession(t, r, "phone", "Dune", 60, 10, "2025-09-18T20:00:00Z", "2025-09-18T20:10:00Z")
+	readSession(t, r, "phone", "Dune", 60, 70, "2025-09-19T20:00:00Z", "2025-09-19T20:00:00Z")
+
+	w := doJSON(t, r, http.MethodGet, "/v1/stats/speed", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Overall struct {
+			PagesPerHour float64 `json:"pages_per_hour"`
+			Sessions     int     `json:"sessions"`
+		} `json:"overall"`
+		ByDevice []struct {
+			DeviceID     string  `json:"device_id"`
+			PagesPerHour float64 `json:"pages_per_hour"`
+		} `json:"by_device"`
+		ByBook []map[string]any `json:"by_book"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+
+	if resp.Overall.Sessions != 2 {
+		t.Fatalf("expected 2 sessions, got %d", resp.Overall.Sessions)
+	}
+	if resp.Overall.PagesPerHour != 30 {
+		t.Fatalf("expected 30 pages/hour, got %v", resp.Overall.PagesPerHour)
+	}
+	if len(resp.ByBook) != 1 {
+		t.Fatalf("expected 1 book, got %d", len(resp.ByBook))
+	}
+	if len(resp.ByDevice) != 2 || resp.ByDevice[0].DeviceID != "ipad" || resp.ByDevice[0].PagesPerHour != 20 {
+		t.Fatalf("unexpected by_device: %+v", resp.ByDevice)
+	}
+}
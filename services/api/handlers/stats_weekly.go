@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"time"
 )
 
 type StatDay struct {
@@ -22,20 +23,21 @@ func (a *App) statsWeekly(w http.ResponseWriter, r *http.Request) {
 			days = n
 		}
 	}
-	offset := -(days - 1)
+	since := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1-days)
 
 	const q = `
 WITH closed AS (
   SELECT
-    DATE(ended_at) AS day,
+    SUBSTR(ended_at, 1, 10) AS day,
     duration_seconds,
     CASE
       WHEN end_page IS NOT NULL AND start_page IS NOT NULL THEN (end_page - start_page)
       ELSE NULL
     END AS pages_read
   FROM sessions
-  WHERE ended_at IS NOT NULL
-    AND DATE(ended_at) >= DATE('now', ? || ' days')
+  WHERE user_id = ?
+    AND ended_at IS NOT NULL
+    AND ended_at >= ?
 ),
 agg AS (
   SELECT
@@ -55,9 +57,9 @@ FROM agg
 ORDER BY day DESC
 LIMIT ?;
 `
-	rows, err := a.DB.Query(q, offset, days)
+	rows, err := a.Store.Query(r.Context(), q, userID(r.Context()), since.Format(time.RFC3339), days)
 	if err != nil {
-		writeErr(w, http.StatusInternalServerError, "query failed")
+		serverError(w, r, err, "query failed")
 		return
 	}
 	defer rows.Close()
@@ -67,7 +69,7 @@ LIMIT ?;
 		var d StatDay
 		var pages *int
 		if err := rows.Scan(&d.DayISO, &d.MinutesRead, &d.SessionsClosed, &pages); err != nil {
-			writeErr(w, http.StatusInternalServerError, "scan failed")
+			serverError(w, r, err, "scan failed")
 			return
 		}
 		if pages != nil {
@@ -81,7 +83,7 @@ LIMIT ?;
 		out = append(out, d)
 	}
 	if err := rows.Err(); err != nil {
-		writeErr(w, http.StatusInternalServerError, "row error")
+		serverError(w, r, err, "row error")
 		return
 	}
 
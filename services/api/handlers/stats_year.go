@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type yearSession struct {
+	ID         int64
+	BookID     int64
+	BookTitle  string
+	Author     *string
+	TotalPages *int
+	DeviceID   string
+	StartPage  int
+	EndPage    *int
+	StartedAt  string
+	EndedAt    time.Time
+	Seconds    int64
+}
+
+type yearLongestSession struct {
+	SessionID       int64  `json:"session_id"`
+	BookID          int64  `json:"book_id"`
+	BookTitle       string `json:"book_title"`
+	DeviceID        string `json:"device_id"`
+	StartedAt       string `json:"started_at"`
+	DurationSeconds int64  `json:"duration_seconds"`
+}
+
+type yearStreak struct {
+	Days  int    `json:"days"`
+	Start string `json:"start,omitempty"`
+	End   string `json:"end,omitempty"`
+}
+
+type yearTopBook struct {
+	BookID    int64   `json:"book_id"`
+	BookTitle string  `json:"book_title"`
+	Author    *string `json:"author,omitempty"`
+	Minutes   float64 `json:"minutes"`
+}
+
+type yearTop struct {
+	Name    string  `json:"name"`
+	Minutes float64 `json:"minutes"`
+}
+
+const yearTopN = 5
+
+func (a *App) statsYear(w http.ResponseWriter, r *http.Request) {
+	year, err := strconv.Atoi(chi.URLParam(r, "yyyy"))
+	if err != nil || year < 1970 || year > 9999 {
+		writeError(w, r, ErrInvalidValue.Field("yyyy", "year must be a four-digit year (e.g., 2025)"))
+		return
+	}
+	from := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(1, 0, 0)
+	fromStr, toStr := from.Format(time.RFC3339), to.Format(time.RFC3339)
+	uid := userID(r.Context())
+
+	// Closed sessions are credited to the year they ended in, as in statsWeekly.
+	rows, err := a.Store.Query(r.Context(), `
+SELECT s.id, s.book_id, b.title, b.author, b.total_pages, s.device_id,
+       s.start_page, s.end_page, s.started_at, s.ended_at, COALESCE(s.duration_seconds, 0)
+FROM sessions s
+JOIN books b ON b.id = s.book_id
+WHERE s.user_id = ? AND s.ended_at >= ? AND s.ended_at < ?
+ORDER BY s.ended_at ASC, s.id ASC;`, uid, fromStr, toStr)
+	if err != nil {
+		serverError(w, r, err, "query failed")
+		return
+	}
+	defer rows.Close()
+
+	var sessions []yearSession
+	for rows.Next() {
+		var s yearSession
+		var endedAt string
+		if err := rows.Scan(&s.ID, &s.BookID, &s.BookTitle, &s.Author, &s.TotalPages, &s.DeviceID,
+			&s.StartPage, &s.EndPage, &s.StartedAt, &endedAt, &s.Seconds); err != nil {
+			serverError(w, r, err, "scan failed")
+			return
+		}
+		if s.EndedAt, err = parseRFC3339UTC(endedAt); err != nil {
+			continue
+		}
+		sessions = append(sessions, s)
+	}
+	if err := rows.Err(); err != nil {
+		serverError(w, r, err, "row error")
+		return
+	}
+
+	// A book is started in the year its first ever session started.
+	var booksStarted int
+	err = a.Store.QueryRow(r.Context(), `
+SELECT COUNT(*)
+FROM (SELECT MIN(started_at) AS first_started FROM sessions WHERE user_id = ? GROUP BY book_id) firsts
+WHERE first_started >= ? AND first_started < ?;`, uid, fromStr, toStr).Scan(&booksStarted)
+	if err != nil {
+		serverError(w, r, err, "query failed")
+		return
+	}
+
+	var totalSeconds int64
+	var longest *yearLongestSession
+	var pace speedStat
+	finished := map[int64]bool{}
+	days := map[string]bool{}
+	bookSec := map[int64]*yearTopBook{}
+	authorSec := map[string]int64{}
+	deviceSec := map[string]int64{}
+	var monthSec [12]int64
+	var weekdaySec [7]int64
+
+	for _, s := range sessions {
+		totalSeconds += s.Seconds
+		days[s.EndedAt.Format(time.DateOnly)] = true
+		monthSec[s.EndedAt.Month()-1] += s.Seconds
+		weekdaySec[s.EndedAt.Weekday()] += s.Seconds
+		deviceSec[s.DeviceID] += s.Seconds
+		if s.Author != nil && *s.Author != "" {
+			authorSec[*s.Author] += s.Seconds
+		}
+
+		b, ok := bookSec[s.BookID]
+		if !ok {
+			b = &yearTopBook{BookID: s.BookID, BookTitle: s.BookTitle, Author: s.Author}
+			bookSec[s.BookID] = b
+		}
+		b.Minutes += float64(s.Seconds) / 60
+
+		if longest == nil || s.Seconds > longest.DurationSeconds {
+			longest = &yearLongestSession{
+				SessionID:       s.ID,
+				BookID:          s.BookID,
+				BookTitle:       s.BookTitle,
+				DeviceID:        s.DeviceID,
+				StartedAt:       s.StartedAt,
+				DurationSeconds: s.Seconds,
+			}
+		}
+		if s.EndPage != nil && s.TotalPages != nil && *s.EndPage >= *s.TotalPages {
+			finished[s.BookID] = true
+		}
+		// Same outlier rules as /v1/stats/speed.
+		if s.EndPage != nil && s.Seconds > 0 && *s.EndPage >= s.StartPage {
+			pace.add(speedSample{Pages: *s.EndPage - s.StartPage, Seconds: s.Seconds})
+		}
+	}
+
+	topBooks := make([]yearTopBook, 0, len(bookSec))
+	for _, b := range bookSec {
+		b.Minutes = round2(b.Minutes)
+		topBooks = append(topBooks, *b)
+	}
+	sort.Slice(topBooks, func(i, j int) bool {
+		if topBooks[i].Minutes != topBooks[j].Minutes {
+			return topBooks[i].Minutes > topBooks[j].Minutes
+		}
+		return topBooks[i].BookID < topBooks[j].BookID
+	})
+	if len(topBooks) > yearTopN {
+		topBooks = topBooks[:yearTopN]
+	}
+
+	topAuthors := rankBySeconds(authorSec)
+	if len(topAuthors) > yearTopN {
+		topAuthors = topAuthors[:yearTopN]
+	}
+
+	var busiestMonth, busiestWeekday, favoriteDevice *yearTop
+	for m, sec := range monthSec {
+		if sec > 0 && (busiestMonth == nil || float64(sec)/60 > busiestMonth.Minutes) {
+			busiestMonth = &yearTop{Name: time.Month(m + 1).String(), Minutes: round2(float64(sec) / 60)}
+		}
+	}
+	for d, sec := range weekdaySec {
+		if sec > 0 && (busiestWeekday == nil || float64(sec)/60 > busiestWeekday.Minutes) {
+			busiestWeekday = &yearTop{Name: time.Weekday(d).String(), Minutes: round2(float64(sec) / 60)}
+		}
+	}
+	if devices := rankBySeconds(deviceSec); len(devices) > 0 {
+		favoriteDevice = &devices[0]
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"year":            year,
+		"books_started":   booksStarted,
+		"books_finished":  len(finished),
+		"sessions":        len(sessions),
+		"total_hours":     round2(float64(totalSeconds) / 3600),
+		"days_read":       len(days),
+		"longest_session": longest,
+		"longest_streak":  longestStreak(days),
+		"top_books":       topBooks,
+		"top_authors":     topAuthors,
+		"busiest_month":   busiestMonth,
+		"busiest_weekday": busiestWeekday,
+		"favorite_device": favoriteDevice,
+		"pages_per_hour":  pace.PagesPerHour,
+	})
+}
+
+// rankBySeconds turns a name→seconds map into entries sorted by time spent,
+// ties broken by name.
+func rankBySeconds(m map[string]int64) []yearTop {
+	out := make([]yearTop, 0, len(m))
+	for name, sec := range m {
+		out = append(out, yearTop{Name: name, Minutes: round2(float64(sec) / 60)})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Minutes != out[j].Minutes {
+			return out[i].Minutes > out[j].Minutes
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+// longestStreak finds the longest run of consecutive days (YYYY-MM-DD keys).
+func longestStreak(days map[string]bool) yearStreak {
+	var best yearStreak
+	for d := range days {
+		t, err := time.Parse(time.DateOnly, d)
+		if err != nil {
+			continue
+		}
+		// Only count from the first day of a run.
+		if days[t.AddDate(0, 0, -1).Format(time.DateOnly)] {
+			continue
+		}
+		n, end := 1, t
+		for days[end.AddDate(0, 0, 1).Format(time.DateOnly)] {
+			end = end.AddDate(0, 0, 1)
+			n++
+		}
+		if n > best.Days || (n == best.Days && d < best.Start) {
+			best = yearStreak{Days: n, Start: d, End: end.Format(time.DateOnly)}
+		}
+	}
+	return best
+}
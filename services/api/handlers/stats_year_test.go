@@ -0,0 +1,84 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestStatsYear_Summary(t *testing.T) {
+	r := newTestServer(t)
+
+	w := doJSON(t, r, http.MethodPost, "/v1/session/start", map[string]any{
+		"device_id":   "phone",
+		"book_title":  "Dune",
+		"author":      "Frank Herbert",
+		"total_pages": 100,
+		"start_page":  0,
+		"started_at":  "2025-03-03T20:00:00Z",
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("start expected 201, got %d body=%s", w.Code, w.Body.String())
+	}
+	w = doJSON(t, r, http.MethodPost, "/v1/session/stop", map[string]any{
+		"device_id": "phone",
+		"end_page":  60,
+		"ended_at":  "2025-03-03T21:00:00Z",
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("stop expected 200, got %d body=%s", w.Code, w.Body.String())
+	}
+	readSession(t, r, "ipad", "Dune", 60, 100, "2025-03-04T20:00:00Z", "2025-03-04T20:30:00Z")
+	readSession(t, r, "phone", "Emma", 0, 10, "2025-07-10T08:00:00Z", "2025-07-10T08:20:00Z")
+	// Different year.
+	readSession(t, r, "phone", "Emma", 10, 20, "2024-12-30T08:00:00Z", "2024-12-30T09:00:00Z")
+
+	w = doJSON(t, r, http.MethodGet, "/v1/stats/year/2025", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		BooksStarted  int     `json:"books_started"`
+		BooksFinished int     `json:"books_finished"`
+		Sessions      int     `json:"sessions"`
+		TotalHours    float64 `json:"total_hours"`
+		LongestStreak struct {
+			Days  int    `json:"days"`
+			Start string `json:"start"`
+		} `json:"longest_streak"`
+		TopBooks []struct {
+			BookTitle string  `json:"book_title"`
+			Minutes   float64 `json:"minutes"`
+		} `json:"top_books"`
+		BusiestMonth struct {
+			Name string `json:"name"`
+		} `json:"busiest_month"`
+		FavoriteDevice struct {
+			Name string `json:"name"`
+		} `json:"favorite_device"`
+		PagesPerHour float64 `json:"pages_per_hour"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+
+	if resp.BooksStarted != 1 || resp.BooksFinished != 1 || resp.Sessions != 3 {
+		t.Fatalf("unexpected counts: %s", w.Body.String())
+	}
+	if resp.TotalHours != 1.83 {
+		t.Fatalf("expected 1.83 hours, got %v", resp.TotalHours)
+	}
+	if resp.LongestStreak.Days != 2 || resp.LongestStreak.Start != "2025-03-03" {
+		t.Fatalf("unexpected streak: %+v", resp.LongestStreak)
+	}
+	if len(resp.TopBooks) != 2 || resp.TopBooks[0].BookTitle != "Dune" || resp.TopBooks[0].Minutes != 90 {
+		t.Fatalf("unexpected top books: %+v", resp.TopBooks)
+	}
+	if resp.BusiestMonth.Name != "March" || resp.FavoriteDevice.Name != "phone" {
+		t.Fatalf("unexpected busiest month/device: %s", w.Body.String())
+	}
+	if resp.PagesPerHour != 60 {
+		t.Fatalf("expected 60 pages/hour, got %v", resp.PagesPerHour)
+	}
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+const (
+	dialectSQLite   = "sqlite"
+	dialectPostgres = "postgres"
+)
+
+// Querier runs SQL written with ? placeholders. Both a Store and the
+// transaction handed to Store.WithTx satisfy it, so repo helpers work the
+// same inside and outside a transaction.
+//
+// Queries must stick to SQL both backends understand: COALESCE rather than
+// IFNULL, RETURNING id rather than LastInsertId, and times compared as the
+// RFC3339 UTC strings they are stored as rather than with date functions.
+type Querier interface {
+	Query(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRow(ctx context.Context, query string, args ...any) *sql.Row
+	Exec(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// Store is the database the handlers talk to.
+type Store interface {
+	Querier
+
+	// WithTx runs fn in a transaction, committing if it returns nil and
+	// rolling back otherwise. fn gets the context to use inside the
+	// transaction, which wrappers may have added to (e.g., a span). fn may
+	// run more than once: SQLite retries the whole transaction when the
+	// database is busy, so fn must not have side effects outside tx until it
+	// succeeds.
+	WithTx(ctx context.Context, fn func(ctx context.Context, tx Querier) error) error
+
+	// Dialect is "sqlite" or "postgres".
+	Dialect() string
+
+	// Backup writes a consistent snapshot of the database to path, which
+	// must not exist yet. Restore replaces the whole database with the
+	// snapshot at path, migrating it first, in one step that concurrent
+	// requests see either side of. Both are SQLite only; PostgreSQL
+	// returns ErrNotSupported.
+	Backup(ctx context.Context, path string) error
+	Restore(ctx context.Context, path string) error
+
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+// sqlStore implements Store on a database/sql handle; the dialect decides
+// how placeholders are written.
+type sqlStore struct {
+	db      *sql.DB
+	dialect string
+}
+
+// NewSQLiteStore wraps an open, migrated SQLite database.
+func NewSQLiteStore(db *sql.DB) Store {
+	return &sqlStore{db: db, dialect: dialectSQLite}
+}
+
+// NewPostgresStore wraps an open, migrated PostgreSQL database.
+func NewPostgresStore(db *sql.DB) Store {
+	return &sqlStore{db: db, dialect: dialectPostgres}
+}
+
+func (s *sqlStore) Dialect() string { return s.dialect }
+
+func (s *sqlStore) Ping(ctx context.Context) error { return s.db.PingContext(ctx) }
+
+func (s *sqlStore) Close() error { return s.db.Close() }
+
+func (s *sqlStore) Query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return s.db.QueryContext(ctx, rebind(s.dialect, query), args...)
+}
+
+func (s *sqlStore) QueryRow(ctx context.Context, query string, args ...any) *sql.Row {
+	return s.db.QueryRowContext(ctx, rebind(s.dialect, query), args...)
+}
+
+func (s *sqlStore) Exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return s.db.ExecContext(ctx, rebind(s.dialect, query), args...)
+}
+
+// txBusyRetries is how many times a SQLite transaction is retried after
+// SQLITE_BUSY, on top of the driver's own busy_timeout.
+const txBusyRetries = 3
+
+func (s *sqlStore) WithTx(ctx context.Context, fn func(ctx context.Context, tx Querier) error) error {
+	for attempt := 0; ; attempt++ {
+		err := s.withTx(ctx, fn)
+		if s.dialect != dialectSQLite || !isBusy(err) || attempt == txBusyRetries {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Duration(attempt+1) * 25 * time.Millisecond):
+		}
+	}
+}
+
+func (s *sqlStore) withTx(ctx context.Context, fn func(ctx context.Context, tx Querier) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(ctx, &sqlTx{tx: tx, dialect: s.dialect}); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+type sqlTx struct {
+	tx      *sql.Tx
+	dialect string
+}
+
+func (t *sqlTx) Query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return t.tx.QueryContext(ctx, rebind(t.dialect, query), args...)
+}
+
+func (t *sqlTx) QueryRow(ctx context.Context, query string, args ...any) *sql.Row {
+	return t.tx.QueryRowContext(ctx, rebind(t.dialect, query), args...)
+}
+
+func (t *sqlTx) Exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return t.tx.ExecContext(ctx, rebind(t.dialect, query), args...)
+}
+
+// isBusy reports whether err is SQLITE_BUSY or one of its extended codes.
+func isBusy(err error) bool {
+	var e *sqlite.Error
+	return errors.As(err, &e) && e.Code()&0xff == sqlite3.SQLITE_BUSY
+}
+
+// rebind turns ? placeholders into $1, $2, … for PostgreSQL. Question marks
+// inside quoted strings are left alone.
+func rebind(dialect, query string) string {
+	if dialect != dialectPostgres || !strings.Contains(query, "?") {
+		return query
+	}
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+	n := 0
+	var quote byte
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '?':
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
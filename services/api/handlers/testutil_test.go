@@ -1,34 +1,135 @@
 package handlers_test
 
 import (
+	"bytes"
 	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync/atomic"
 	"testing"
 
 	"github.com/mk-slmn/booksmart/services/api/handlers"
 	_ "modernc.org/sqlite"
 )
 
-func newTestDB(t *testing.T) *sql.DB {
+// newTestDB returns a migrated, empty store. The suite runs against SQLite
+// in memory unless BOOKSMART_TEST_POSTGRES holds a PostgreSQL URL, in which
+// case every test gets a schema of its own in that database.
+func newTestDB(t *testing.T) handlers.Store {
 	t.Helper()
 
-	db, err := sql.Open("sqlite", ":memory:")
+	if dsn := os.Getenv("BOOKSMART_TEST_POSTGRES"); dsn != "" {
+		return newTestPostgres(t, dsn)
+	}
+
+	db, err := sql.Open("sqlite", "file::memory:?_pragma=foreign_keys(1)")
 	if err != nil {
 		t.Fatalf("failed to open in-memory DB: %v", err)
 	}
+	// One connection, one in-memory database.
+	db.SetMaxOpenConns(1)
 
 	t.Cleanup(func() { _ = db.Close() })
 
-	if _, err := db.Exec(handlers.ReadSchema()); err != nil {
-		t.Fatalf("failed to apply schema: %v", err)
+	if err := handlers.Migrate(db); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	return handlers.NewSQLiteStore(db)
+}
+
+func newTestPostgres(t *testing.T, dsn string) handlers.Store {
+	t.Helper()
+
+	admin, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatalf("open postgres: %v", err)
+	}
+	t.Cleanup(func() { _ = admin.Close() })
+
+	schema := fmt.Sprintf("test_%d_%d", os.Getpid(), testSchemaSeq.Add(1))
+	if _, err := admin.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() { _, _ = admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`) })
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		t.Fatalf("parse BOOKSMART_TEST_POSTGRES: %v", err)
 	}
+	q := u.Query()
+	q.Set("search_path", schema)
+	u.RawQuery = q.Encode()
 
-	return db
+	store, err := handlers.OpenStore(u.String())
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	return store
 }
 
+var testSchemaSeq atomic.Int64
+
 func newTestServer(t *testing.T) http.Handler {
 	t.Helper()
 
-	db := newTestDB(t)
-	return handlers.NewServer(db)
+	return newTestRouter(newTestDB(t))
+}
+
+// quietLogger drops request logs, so test output shows only failures.
+var quietLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// newTestRouter is NewServer with request logs discarded.
+func newTestRouter(store handlers.Store) http.Handler {
+	return (&handlers.App{Store: store, Logger: quietLogger}).Routes()
+}
+
+// doJSON sends body (if non-nil) as JSON and returns the recorded response.
+func doJSON(t *testing.T, r http.Handler, method, path string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var req *http.Request
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("marshal body: %v", err)
+		}
+		req = httptest.NewRequest(method, path, bytes.NewReader(b))
+		req.Header.Set("Content-Type", "application/json")
+	} else {
+		req = httptest.NewRequest(method, path, nil)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// readSession starts and stops a session on device for title.
+func readSession(t *testing.T, r http.Handler, device, title string, startPage, endPage int, startedAt, endedAt string) {
+	t.Helper()
+
+	w := doJSON(t, r, http.MethodPost, "/v1/session/start", map[string]any{
+		"device_id":  device,
+		"book_title": title,
+		"start_page": startPage,
+		"started_at": startedAt,
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("start expected 201, got %d body=%s", w.Code, w.Body.String())
+	}
+	w = doJSON(t, r, http.MethodPost, "/v1/session/stop", map[string]any{
+		"device_id": device,
+		"end_page":  endPage,
+		"ended_at":  endedAt,
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("stop expected 200, got %d body=%s", w.Code, w.Body.String())
+	}
 }
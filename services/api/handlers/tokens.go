@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// tokenPrefix marks Booksmart tokens so they are easy to spot in configs.
+const tokenPrefix = "bsm_"
+
+type createTokenRequest struct {
+	Name  string `json:"name"`
+	Scope string `json:"scope"`
+	User  string `json:"user,omitempty"` // default user only: mint a token for another user
+
+	// DeviceID binds the token to a registered device, which then stands in
+	// for device_id on session requests.
+	DeviceID string `json:"device_id,omitempty"`
+}
+
+type tokenItem struct {
+	ID         int64   `json:"id"`
+	UserID     int64   `json:"user_id"`
+	Name       string  `json:"name"`
+	Prefix     string  `json:"prefix"`
+	Scope      string  `json:"scope"`
+	DeviceID   *string `json:"device_id,omitempty"`
+	CreatedAt  string  `json:"created_at"`
+	LastUsedAt *string `json:"last_used_at,omitempty"`
+	RevokedAt  *string `json:"revoked_at,omitempty"`
+}
+
+type createTokenResponse struct {
+	tokenItem
+	Token string `json:"token"` // only ever returned here
+}
+
+func (a *App) createToken(w http.ResponseWriter, r *http.Request) {
+	var req createTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, ErrInvalidJSON)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	req.User = strings.TrimSpace(req.User)
+	req.DeviceID = strings.TrimSpace(req.DeviceID)
+	if req.Name == "" {
+		writeError(w, r, ErrRequired.Field("name", "name is required"))
+		return
+	}
+	if req.Scope == "" {
+		req.Scope = scopeWrite
+	}
+	if req.Scope != scopeRead && req.Scope != scopeWrite {
+		writeError(w, r, ErrInvalidValue.Field("scope", "scope must be read or write"))
+		return
+	}
+
+	uid := userID(r.Context())
+	if req.User != "" {
+		if !isAdmin(r.Context()) {
+			writeError(w, r, ErrDefaultUserOnly.WithMessage("only the default user can create tokens for other users"))
+			return
+		}
+		err := a.Store.QueryRow(r.Context(), `SELECT id FROM users WHERE name = ?`, req.User).Scan(&uid)
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, r, ErrUserNotFound)
+			return
+		}
+		if err != nil {
+			serverError(w, r, err, "internal error")
+			return
+		}
+	}
+
+	// A device token only mints tokens for its own device, so a leaked one
+	// cannot be traded for a token that works everywhere.
+	if bound := boundDevice(r.Context()); bound != "" {
+		if req.DeviceID != "" && req.DeviceID != bound {
+			writeError(w, r, ErrDeviceMismatch.Field("device_id", "a device token can only create tokens for its own device"))
+			return
+		}
+		req.DeviceID = bound
+	}
+
+	var device *string
+	if req.DeviceID != "" {
+		var n int
+		err := a.Store.QueryRow(r.Context(), `SELECT COUNT(*) FROM devices WHERE user_id = ? AND device_id = ?`, uid, req.DeviceID).Scan(&n)
+		if err != nil {
+			serverError(w, r, err, "internal error")
+			return
+		}
+		if n == 0 {
+			writeError(w, r, ErrUnknownDevice.Field("device_id", "device_id must be a registered device"))
+			return
+		}
+		device = &req.DeviceID
+	}
+
+	raw, err := newToken()
+	if err != nil {
+		serverError(w, r, err, "internal error")
+		return
+	}
+
+	out := createTokenResponse{
+		tokenItem: tokenItem{
+			UserID:    uid,
+			Name:      req.Name,
+			Prefix:    raw[:len(tokenPrefix)+6],
+			Scope:     req.Scope,
+			DeviceID:  device,
+			CreatedAt: timeOrNowRFC3339(nil),
+		},
+		Token: raw,
+	}
+	err = a.Store.QueryRow(r.Context(), `
+		INSERT INTO api_tokens (user_id, name, prefix, token_hash, scope, device_id, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`, uid, out.Name, out.Prefix, hashToken(raw), out.Scope, out.DeviceID, out.CreatedAt).Scan(&out.ID)
+	if err != nil {
+		serverError(w, r, err, "internal error")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, out)
+}
+
+func (a *App) listTokens(w http.ResponseWriter, r *http.Request) {
+	rows, err := a.Store.Query(r.Context(), `
+		SELECT id, user_id, name, prefix, scope, device_id, created_at, last_used_at, revoked_at
+		FROM api_tokens
+		WHERE user_id = ?
+		ORDER BY id
+	`, userID(r.Context()))
+	if err != nil {
+		serverError(w, r, err, "query failed")
+		return
+	}
+	defer rows.Close()
+
+	items := make([]tokenItem, 0)
+	for rows.Next() {
+		var it tokenItem
+		if err := rows.Scan(&it.ID, &it.UserID, &it.Name, &it.Prefix, &it.Scope, &it.DeviceID, &it.CreatedAt, &it.LastUsedAt, &it.RevokedAt); err != nil {
+			serverError(w, r, err, "scan failed")
+			return
+		}
+		items = append(items, it)
+	}
+	if err := rows.Err(); err != nil {
+		serverError(w, r, err, "row error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"items": items,
+		"meta":  map[string]any{"count": len(items)},
+	})
+}
+
+func (a *App) revokeToken(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	res, err := a.Store.Exec(r.Context(), `
+		UPDATE api_tokens
+		SET revoked_at = ?
+		WHERE id = ? AND user_id = ? AND revoked_at IS NULL
+	`, timeOrNowRFC3339(nil), id, userID(r.Context()))
+	if err != nil {
+		serverError(w, r, err, "internal error")
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		writeError(w, r, ErrTokenNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return tokenPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
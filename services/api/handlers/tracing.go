@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/mk-slmn/booksmart/services/api/handlers"
+
+// propagator reads W3C traceparent/tracestate and baggage headers.
+var propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+func (a *App) tracerProvider() trace.TracerProvider {
+	if a.TracerProvider != nil {
+		return a.TracerProvider
+	}
+	return otel.GetTracerProvider()
+}
+
+// tracing starts a server span per request, continuing the caller's trace
+// when the request carries trace context. The span is renamed to the chi
+// route pattern once routing is done.
+func (a *App) tracing(next http.Handler) http.Handler {
+	tracer := a.tracerProvider().Tracer(tracerName)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, r.Method, trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("url.path", r.URL.Path),
+				attribute.String("http.request_id", middleware.GetReqID(r.Context())),
+			))
+		defer span.End()
+
+		entry := entryFrom(ctx)
+		if entry != nil && span.SpanContext().IsValid() {
+			entry.traceID = span.SpanContext().TraceID().String()
+		}
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		if rc := chi.RouteContext(r.Context()); rc != nil && rc.RoutePattern() != "" {
+			span.SetName(r.Method + " " + rc.RoutePattern())
+			span.SetAttributes(attribute.String("http.route", rc.RoutePattern()))
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if entry != nil {
+			if entry.userID != 0 {
+				span.SetAttributes(attribute.Int64("booksmart.user_id", entry.userID))
+			}
+			if entry.deviceID != "" {
+				span.SetAttributes(attribute.String("booksmart.device_id", entry.deviceID))
+			}
+			if entry.err != nil {
+				span.RecordError(entry.err)
+			}
+		}
+		if status >= 500 {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
+}
+
+// startSpan starts a span for a repository call under the span in ctx,
+// using the same tracer provider. Without a span in ctx it is a no-op.
+func startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	tp := trace.SpanFromContext(ctx).TracerProvider()
+	return tp.Tracer(tracerName).Start(ctx, name)
+}
+
+// endSpan records err on span, except sql.ErrNoRows, which repository calls
+// use to mean "not found", and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// traceStore wraps store so every database call gets a client span with
+// the statement it ran.
+func traceStore(store Store) Store {
+	return &tracedStore{Store: store, q: tracedQuerier{q: store, system: dbSystem(store.Dialect())}}
+}
+
+func dbSystem(dialect string) string {
+	if dialect == dialectPostgres {
+		return "postgresql"
+	}
+	return dialect
+}
+
+type tracedQuerier struct {
+	q      Querier
+	system string
+}
+
+func (q tracedQuerier) start(ctx context.Context, query string) (context.Context, trace.Span) {
+	stmt := strings.Join(strings.Fields(query), " ")
+	op, _, _ := strings.Cut(stmt, " ")
+	ctx, span := startSpan(ctx, "db "+strings.ToUpper(op))
+	span.SetAttributes(
+		attribute.String("db.system", q.system),
+		attribute.String("db.operation.name", strings.ToUpper(op)),
+		attribute.String("db.query.text", stmt),
+	)
+	return ctx, span
+}
+
+func (q tracedQuerier) Query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	ctx, span := q.start(ctx, query)
+	rows, err := q.q.Query(ctx, query, args...)
+	endSpan(span, err)
+	return rows, err
+}
+
+func (q tracedQuerier) QueryRow(ctx context.Context, query string, args ...any) *sql.Row {
+	ctx, span := q.start(ctx, query)
+	row := q.q.QueryRow(ctx, query, args...)
+	endSpan(span, row.Err())
+	return row
+}
+
+func (q tracedQuerier) Exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, span := q.start(ctx, query)
+	res, err := q.q.Exec(ctx, query, args...)
+	endSpan(span, err)
+	return res, err
+}
+
+type tracedStore struct {
+	Store
+	q tracedQuerier
+}
+
+func (s *tracedStore) Query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return s.q.Query(ctx, query, args...)
+}
+
+func (s *tracedStore) QueryRow(ctx context.Context, query string, args ...any) *sql.Row {
+	return s.q.QueryRow(ctx, query, args...)
+}
+
+func (s *tracedStore) Exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return s.q.Exec(ctx, query, args...)
+}
+
+// WithTx wraps the transaction in a span and hands fn a context carrying
+// it, so the calls fn makes with that context become its children. Retried
+// attempts show up as repeated children.
+func (s *tracedStore) WithTx(ctx context.Context, fn func(ctx context.Context, tx Querier) error) error {
+	ctx, span := startSpan(ctx, "db transaction")
+	span.SetAttributes(attribute.String("db.system", s.q.system))
+	attempts := 0
+	err := s.Store.WithTx(ctx, func(ctx context.Context, tx Querier) error {
+		attempts++
+		return fn(ctx, tracedQuerier{q: tx, system: s.q.system})
+	})
+	span.SetAttributes(attribute.Int("db.transaction.attempts", attempts))
+	endSpan(span, err)
+	return err
+}
@@ -0,0 +1,105 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mk-slmn/booksmart/services/api/handlers"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracing_SpansForRequestAndRepositoryCalls(t *testing.T) {
+	exp := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp))
+	t.Cleanup(func() { _ = tp.Shutdown(t.Context()) })
+	r := (&handlers.App{Store: newTestDB(t), Logger: quietLogger, TracerProvider: tp}).Routes()
+
+	doJSON(t, r, http.MethodPost, "/v1/session/start", map[string]any{"device_id": "ipad", "book_title": "Dune"})
+	exp.Reset()
+
+	// The second start closes the first session before creating a new one.
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	body, _ := json.Marshal(map[string]any{"device_id": "ipad", "book_title": "Dune"})
+	req := httptest.NewRequest(http.MethodPost, "/v1/session/start", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("start expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	spans := exp.GetSpans()
+	byName := map[string]tracetest.SpanStub{}
+	for _, s := range spans {
+		if s.SpanContext.TraceID().String() != traceID {
+			t.Errorf("span %q not in the incoming trace: %s", s.Name, s.SpanContext.TraceID())
+		}
+		byName[s.Name] = s
+	}
+
+	server, ok := byName["POST /v1/session/start"]
+	if !ok {
+		t.Fatalf("no server span among %v", names(spans))
+	}
+	if server.SpanKind != trace.SpanKindServer || server.Parent.SpanID().String() != "00f067aa0ba902b7" {
+		t.Fatalf("server span should be a child of the remote parent: %+v", server.Parent)
+	}
+	if !hasAttr(server.Attributes, attribute.Int("http.response.status_code", http.StatusCreated)) {
+		t.Errorf("server span missing status code: %v", server.Attributes)
+	}
+
+	tx, ok := byName["db transaction"]
+	if !ok {
+		t.Fatalf("no db transaction span among %v", names(spans))
+	}
+	if tx.Parent.SpanID() != server.SpanContext.SpanID() {
+		t.Errorf("db transaction should be a child of the server span")
+	}
+	// Repository calls made inside the transaction hang off it.
+	for _, name := range []string{"openSessionByDevice", "closeSession", "findBookIDByTitle", "insertSession"} {
+		s, ok := byName[name]
+		if !ok {
+			t.Errorf("missing %s span among %v", name, names(spans))
+			continue
+		}
+		if s.Parent.SpanID() != tx.SpanContext.SpanID() {
+			t.Errorf("%s should be a child of the db transaction span", name)
+		}
+	}
+
+	// Each repository call has the statement it ran as a child span.
+	insert := byName["insertSession"].SpanContext.SpanID()
+	var found bool
+	for _, s := range spans {
+		if s.Name == "db INSERT" && s.Parent.SpanID() == insert {
+			found = hasAttr(s.Attributes, attribute.String("db.system", "sqlite"))
+		}
+	}
+	if !found {
+		t.Errorf("expected a db INSERT span under insertSession")
+	}
+}
+
+func names(spans tracetest.SpanStubs) []string {
+	out := make([]string, len(spans))
+	for i, s := range spans {
+		out[i] = s.Name
+	}
+	return out
+}
+
+func hasAttr(attrs []attribute.KeyValue, want attribute.KeyValue) bool {
+	for _, a := range attrs {
+		if a == want {
+			return true
+		}
+	}
+	return false
+}
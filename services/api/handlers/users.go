@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// defaultUserID owns everything created before accounts existed, and is who
+// requests act as when they do not name a user.
+const defaultUserID int64 = 1
+
+// userHeader names the user a request acts as while no tokens exist.
+const userHeader = "X-Booksmart-User"
+
+type ctxKey int
+
+const (
+	userCtxKey ctxKey = iota
+	deviceCtxKey
+	logCtxKey
+)
+
+type userItem struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	CreatedAt string `json:"created_at"`
+}
+
+type createUserRequest struct {
+	Name string `json:"name"`
+}
+
+// userID returns the user the request is scoped to.
+func userID(ctx context.Context) int64 {
+	if id, ok := ctx.Value(userCtxKey).(int64); ok {
+		return id
+	}
+	return defaultUserID
+}
+
+func withUserID(ctx context.Context, id int64) context.Context {
+	return context.WithValue(ctx, userCtxKey, id)
+}
+
+// isAdmin reports whether the request acts as the default user, who manages
+// the other accounts.
+func isAdmin(ctx context.Context) bool {
+	return userID(ctx) == defaultUserID
+}
+
+func (a *App) createUser(w http.ResponseWriter, r *http.Request) {
+	if !isAdmin(r.Context()) {
+		writeError(w, r, ErrDefaultUserOnly.WithMessage("only the default user can create users"))
+		return
+	}
+	var req createUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, ErrInvalidJSON)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		writeError(w, r, ErrRequired.Field("name", "name is required"))
+		return
+	}
+
+	out := userItem{Name: req.Name, CreatedAt: timeOrNowRFC3339(nil)}
+	err := a.Store.WithTx(r.Context(), func(ctx context.Context, tx Querier) error {
+		var exists int
+		if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM users WHERE name = ?`, req.Name).Scan(&exists); err != nil {
+			return err
+		}
+		if exists > 0 {
+			return ErrUserExists
+		}
+		return tx.QueryRow(ctx, `INSERT INTO users (name, created_at) VALUES (?, ?) RETURNING id`, out.Name, out.CreatedAt).
+			Scan(&out.ID)
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, out)
+}
+
+func (a *App) listUsers(w http.ResponseWriter, r *http.Request) {
+	rows, err := a.Store.Query(r.Context(), `SELECT id, name, created_at FROM users ORDER BY id`)
+	if err != nil {
+		serverError(w, r, err, "query failed")
+		return
+	}
+	defer rows.Close()
+
+	items := make([]userItem, 0)
+	for rows.Next() {
+		var it userItem
+		if err := rows.Scan(&it.ID, &it.Name, &it.CreatedAt); err != nil {
+			serverError(w, r, err, "scan failed")
+			return
+		}
+		items = append(items, it)
+	}
+	if err := rows.Err(); err != nil {
+		serverError(w, r, err, "row error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"items": items,
+		"meta":  map[string]any{"count": len(items)},
+	})
+}
+
+func (a *App) currentUser(w http.ResponseWriter, r *http.Request) {
+	var out userItem
+	err := a.Store.QueryRow(r.Context(), `SELECT id, name, created_at FROM users WHERE id = ?`, userID(r.Context())).
+		Scan(&out.ID, &out.Name, &out.CreatedAt)
+	if err != nil {
+		serverError(w, r, err, "query failed")
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
@@ -0,0 +1,93 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// asUser sends a JSON request acting as the named user.
+func asUser(t *testing.T, r http.Handler, user, method, path string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var b []byte
+	if body != nil {
+		b, _ = json.Marshal(body)
+	}
+	req := httptest.NewRequest(method, path, bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Booksmart-User", user)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestUsers_LibrariesAreIsolated(t *testing.T) {
+	r := newTestServer(t)
+
+	for _, name := range []string{"alice", "bob"} {
+		if w := doJSON(t, r, http.MethodPost, "/v1/users", map[string]any{"name": name}); w.Code != http.StatusCreated {
+			t.Fatalf("create %s expected 201, got %d body=%s", name, w.Code, w.Body.String())
+		}
+	}
+
+	// Same title and device for both users.
+	for _, name := range []string{"alice", "bob"} {
+		w := asUser(t, r, name, http.MethodPost, "/v1/session/start", map[string]any{
+			"device_id":  "ipad",
+			"book_title": "Dune",
+			"start_page": 1,
+		})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("start as %s expected 201, got %d body=%s", name, w.Code, w.Body.String())
+		}
+	}
+
+	w := asUser(t, r, "alice", http.MethodPost, "/v1/session/stop", map[string]any{"device_id": "ipad", "end_page": 10})
+	if w.Code != http.StatusOK {
+		t.Fatalf("stop expected 200, got %d body=%s", w.Code, w.Body.String())
+	}
+	// Bob's session on the same device is still open.
+	w = asUser(t, r, "bob", http.MethodGet, "/v1/sessions/open?device_id=ipad", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("bob open expected 200, got %d body=%s", w.Code, w.Body.String())
+	}
+
+	var books struct {
+		Items []struct {
+			ID int64 `json:"id"`
+		} `json:"items"`
+	}
+	w = asUser(t, r, "alice", http.MethodGet, "/v1/books", nil)
+	_ = json.Unmarshal(w.Body.Bytes(), &books)
+	if len(books.Items) != 1 {
+		t.Fatalf("alice expected 1 book, got %s", w.Body.String())
+	}
+
+	// The default user sees neither library.
+	w = doJSON(t, r, http.MethodGet, "/v1/sessions", nil)
+	var sessions struct {
+		Items []any `json:"items"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &sessions)
+	if len(sessions.Items) != 0 {
+		t.Fatalf("default user expected no sessions, got %s", w.Body.String())
+	}
+
+	// Bob cannot read Alice's book by id.
+	w = asUser(t, r, "bob", http.MethodGet, "/v1/books/1", nil)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("cross-user book expected 404, got %d", w.Code)
+	}
+}
+
+func TestUsers_UnknownUserRejected(t *testing.T) {
+	r := newTestServer(t)
+
+	w := asUser(t, r, "mallory", http.MethodGet, "/v1/books", nil)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d body=%s", w.Code, w.Body.String())
+	}
+}
@@ -1,10 +1,13 @@
 package handlers
 
 import (
-	"database/sql"
 	"encoding/json"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/go-chi/chi/v5"
 )
 
 func writeJSON(w http.ResponseWriter, code int, v any) {
@@ -13,27 +16,6 @@ func writeJSON(w http.ResponseWriter, code int, v any) {
 	_ = json.NewEncoder(w).Encode(v)
 }
 
-func writeErr(w http.ResponseWriter, code int, msg string) {
-	writeJSON(w, code, map[string]any{
-		"error": map[string]any{
-			"code":    http.StatusText(code),
-			"message": msg,
-		},
-	})
-}
-
-func withTx(db *sql.DB, fn func(*sql.Tx) error) error {
-	tx, err := db.Begin()
-	if err != nil {
-		return err
-	}
-	if err := fn(tx); err != nil {
-		_ = tx.Rollback()
-		return err
-	}
-	return tx.Commit()
-}
-
 func parseRFC3339UTC(s string) (time.Time, error) {
 	t, err := time.Parse(time.RFC3339, s)
 	if err != nil {
@@ -48,3 +30,41 @@ func timeOrNowRFC3339(t *time.Time) string {
 	}
 	return t.UTC().Format(time.RFC3339)
 }
+
+// parseDateOrRFC3339 accepts a full timestamp or a YYYY-MM-DD date in loc.
+// With endOfDay, a bare date means the start of the following day, so the
+// date itself is included in a half-open range.
+func parseDateOrRFC3339(s string, loc *time.Location, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.In(loc), nil
+	}
+	t, err := time.ParseInLocation(time.DateOnly, s, loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// pathID parses the {id} URL parameter as a positive integer.
+func pathID(r *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, ErrInvalidID.Field("id", "id must be a positive integer")
+	}
+	return id, nil
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapeLike makes s match only itself in a LIKE pattern ending in
+// ESCAPE '\'.
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -3,7 +3,6 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
-	"os"
 )
 
 var (
@@ -17,19 +16,19 @@ type versionResponse struct {
 }
 
 func (a *App) version(w http.ResponseWriter, r *http.Request) {
-	name := appName
-	version := appVersion
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(a.versionInfo())
+}
 
-	if v := os.Getenv("APP_NAME"); v != "" {
-		name = v
+// versionInfo is the app's name and version, falling back to the built-in
+// defaults.
+func (a *App) versionInfo() versionResponse {
+	v := versionResponse{Name: appName, Version: appVersion}
+	if a.AppName != "" {
+		v.Name = a.AppName
 	}
-	if v := os.Getenv("APP_VERSION"); v != "" {
-		version = v
+	if a.AppVersion != "" {
+		v.Version = a.AppVersion
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(versionResponse{
-		Name:    name,
-		Version: version,
-	})
+	return v
 }
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/mk-slmn/booksmart/services/api/config"
+	"github.com/mk-slmn/booksmart/services/api/handlers"
+)
+
+// importAppleBooks runs "booksmart import-applebooks [-user name] FILE",
+// importing a copied Apple Books library database into the configured
+// database. It takes its database settings from the environment and
+// BOOKSMART_CONFIG like the server, and returns the exit code.
+func importAppleBooks(args []string) int {
+	fs := flag.NewFlagSet("import-applebooks", flag.ContinueOnError)
+	user := fs.String("user", "default", "user whose library receives the books")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: booksmart import-applebooks [-user name] BKLibrary-….sqlite")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	cfg, err := config.Load(nil, os.Getenv)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "load config:", err)
+		return 2
+	}
+	store, err := handlers.OpenStore(cfg.DSN())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "open database:", err)
+		return 1
+	}
+	defer store.Close()
+
+	res, err := handlers.ImportAppleBooks(context.Background(), store, *user, fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "import:", err)
+		return 1
+	}
+	fmt.Printf("imported %s: %d books created, %d updated, %d skipped\n", fs.Arg(0), res.Created, res.Updated, res.Skipped)
+	return 0
+}
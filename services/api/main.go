@@ -1,29 +1,148 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/mk-slmn/booksmart/services/api/config"
 	"github.com/mk-slmn/booksmart/services/api/handlers"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 )
 
 func main() {
-	db, err := handlers.OpenDB()
+	if len(os.Args) > 1 && os.Args[1] == "import-applebooks" {
+		os.Exit(importAppleBooks(os.Args[2:]))
+	}
+
+	cfg, err := config.Load(os.Args[1:], os.Getenv)
 	if err != nil {
-		log.Fatal(err)
+		slog.Error("load config", "error", err)
+		os.Exit(2)
+	}
+
+	logger := newLogger(cfg)
+	slog.SetDefault(logger)
+
+	store, err := handlers.OpenStore(cfg.DSN())
+	if err != nil {
+		logger.Error("open database", "error", err)
+		os.Exit(1)
+	}
+	defer func() {
+		if err := store.Close(); err != nil {
+			logger.Error("close database", "error", err)
+		}
+	}()
+
+	tp, err := newTracerProvider(cfg)
+	if err != nil {
+		logger.Error("set up tracing", "error", err)
+		os.Exit(1)
+	}
+	// Flush buffered spans once the drained requests have ended theirs.
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := tp.Shutdown(ctx); err != nil {
+			logger.Error("shut down tracing", "error", err)
+		}
+	}()
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	app := &handlers.App{
+		Store:         store,
+		AppName:       cfg.AppName,
+		AppVersion:    cfg.AppVersion,
+		StrictDevices: cfg.StrictDevices,
+		BackupDir:     cfg.BackupDir,
+		Logger:        logger,
+
+		TracerProvider: tp,
+	}
+	srv := &http.Server{
+		Addr:              cfg.Addr(),
+		Handler:           app.Routes(),
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
 	}
-	defer db.Close()
 
-	r := handlers.NewServer(db)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8787"
+	if cfg.BackupInterval > 0 {
+		go app.RunBackups(ctx, cfg.BackupInterval, cfg.BackupKeep)
 	}
 
-	log.Printf("listening on :%s", port)
-	if err := http.ListenAndServe(":"+port, r); err != nil {
-		log.Fatal(err)
+	errc := make(chan error, 1)
+	go func() {
+		logger.Info("listening", "addr", srv.Addr, "database", store.Dialect(), "otlp_endpoint", cfg.OTLPEndpoint)
+		errc <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errc:
+		if !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("serve", "error", err)
+		}
+		return
+	case <-ctx.Done():
+	}
+
+	// Stop accepting connections and let in-flight requests, and the
+	// transactions they hold, finish before the store is closed.
+	logger.Info("shutting down", "drain_timeout", cfg.ShutdownTimeout.String())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Error("shutdown", "error", err)
+	}
+}
+
+func newLogger(cfg config.Config) *slog.Logger {
+	var level slog.Level
+	_ = level.UnmarshalText([]byte(cfg.LogLevel)) // checked by config.Validate
+	opts := &slog.HandlerOptions{Level: level}
+	if cfg.LogFormat == "text" {
+		return slog.New(slog.NewTextHandler(os.Stderr, opts))
+	}
+	return slog.New(slog.NewJSONHandler(os.Stderr, opts))
+}
+
+// newTracerProvider samples cfg.TraceSampleRatio of new traces and batches
+// them to the OTLP/HTTP collector at cfg.OTLPEndpoint. Without an endpoint
+// spans are still created, so trace IDs reach the logs, but go nowhere.
+func newTracerProvider(cfg config.Config) (*sdktrace.TracerProvider, error) {
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(cfg.AppName),
+		semconv.ServiceVersion(cfg.AppVersion),
+	))
+	if err != nil {
+		return nil, err
+	}
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.TraceSampleRatio))),
+	}
+	if cfg.OTLPEndpoint != "" {
+		exp, err := otlptracehttp.New(context.Background(), otlptracehttp.WithEndpointURL(cfg.OTLPEndpoint))
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, sdktrace.WithBatcher(exp))
 	}
+	return sdktrace.NewTracerProvider(opts...), nil
 }